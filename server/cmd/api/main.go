@@ -9,7 +9,7 @@ import (
 	"syscall"
 	"time"
 
-	"server/internal/server"
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/server"
 )
 
 func gracefulShutdown(apiServer *http.Server) {
@@ -0,0 +1,226 @@
+// Package hub embeds the ghost cursor hub in another Go service. It exposes
+// the Manager that tracks clients and routes their events, with options for
+// storage, authentication, logging and the settings the standalone server
+// reads from its environment, and mounts the same WebSocket, SSE and REST
+// routes on the service's own router:
+//
+//	h, err := hub.New(hub.WithAuthenticator(auth))
+//	if err != nil {
+//		return err
+//	}
+//	defer h.Close(context.Background())
+//	h.RegisterEvent("ping", func(event hub.Event, c *hub.Client) error {
+//		pong, err := hub.NewEvent("pong", nil)
+//		if err != nil {
+//			return err
+//		}
+//		c.Send(pong)
+//		return nil
+//	})
+//	h.Mount(router.Group("/cursors"))
+package hub
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/server"
+)
+
+// Manager tracks the connected clients and routes their events.
+type Manager = server.Manager
+
+// Client is a connection to the hub: a cursor, a spectator or a virtual
+// client driven over the ingestion API.
+type Client = server.Client
+
+// Position is a point on the canvas.
+type Position = server.Position
+
+// State is a cursor's position and motion.
+type State = server.State
+
+// Event is a message between a client and the hub.
+type Event = server.Event
+
+// EventHandler handles an event of a registered type sent by a client.
+type EventHandler = server.EventHandler
+
+// Authenticator identifies the user behind a connection request.
+type Authenticator = server.Authenticator
+
+// NewEvent marshals v as the payload of an event of the given type.
+func NewEvent(eventType string, v interface{}) (Event, error) {
+	return server.NewEvent(eventType, v)
+}
+
+// Option configures the hub.
+type Option func(*settings)
+
+type settings struct {
+	store   Store
+	manager []server.Option
+}
+
+// WithStore keeps the hub's users, sessions and room content in store rather
+// than the Postgres database configured by the DB_* environment variables.
+func WithStore(store Store) Option {
+	return func(s *settings) {
+		s.store = store
+	}
+}
+
+// WithAuthenticator makes connections authenticate with auth rather than name
+// themselves with ?username=. The usernames it returns are taken as they are.
+func WithAuthenticator(auth Authenticator) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithAuthenticator(auth))
+	}
+}
+
+// WithLogger sends the hub's logs to logger instead of the standard logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithLogger(logger))
+	}
+}
+
+// WithTrustedProxies lists the addresses or CIDR ranges of the proxies whose
+// X-Forwarded-For headers are believed by Handler. Routes mounted on the
+// service's own router follow its engine's trusted proxies instead.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithTrustedProxies(proxies...))
+	}
+}
+
+// WithAdminToken enables the /admin API for requests carrying token as a
+// bearer token.
+func WithAdminToken(token string) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithAdminToken(token))
+	}
+}
+
+// WithIngestKeys enables the position ingestion API for requests carrying one
+// of keys in the X-API-Key header.
+func WithIngestKeys(keys ...string) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithIngestKeys(keys...))
+	}
+}
+
+// WithInterest sets the cell size of the spatial index, how far beyond a
+// client's viewport cursors are still sent, and the most cursors a client is
+// sent, with 0 for no limit.
+func WithInterest(cellSize, margin float64, maxCursors int) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithInterest(cellSize, margin, maxCursors))
+	}
+}
+
+// WithReactions limits each client to rate reactions a second, with bursts of
+// up to burst, and keeps reactions on the canvas for ttl.
+func WithReactions(rate float64, burst int, ttl time.Duration) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithReactions(rate, burst, ttl))
+	}
+}
+
+// WithWebhookRetries makes up to attempts deliveries of each webhook event,
+// waiting backoff before the first retry and doubling it up to maxBackoff.
+func WithWebhookRetries(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithWebhookRetries(attempts, backoff, maxBackoff))
+	}
+}
+
+// WithPrivateWebhooks lets webhooks target loopback, link-local and private
+// addresses.
+func WithPrivateWebhooks(allow bool) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithPrivateWebhooks(allow))
+	}
+}
+
+// WithVirtualClientTTL disconnects virtual clients that haven't been updated
+// within ttl.
+func WithVirtualClientTTL(ttl time.Duration) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithVirtualClientTTL(ttl))
+	}
+}
+
+// WithUsernameRules sets the length limits of the usernames clients choose
+// and words they may not contain.
+func WithUsernameRules(minLength, maxLength int, denyWords ...string) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithUsernameRules(minLength, maxLength, denyWords...))
+	}
+}
+
+// WithGhosts keeps disconnected cursors drifting for ttl, slowing with the
+// decay time constant.
+func WithGhosts(ttl, decay time.Duration) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithGhosts(ttl, decay))
+	}
+}
+
+// WithTrails sets how much of each cursor's trail is kept in rooms that don't
+// set their own window, and the most trail points kept for each second of it.
+func WithTrails(window time.Duration, maxRate int) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithTrails(window, maxRate))
+	}
+}
+
+// WithPresenceInterval sets how often presence streams check for changes.
+func WithPresenceInterval(interval time.Duration) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithPresenceInterval(interval))
+	}
+}
+
+// WithTagRadius sets the radius of the circle each cursor is treated as in
+// games of tag.
+func WithTagRadius(radius float64) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithTagRadius(radius))
+	}
+}
+
+// WithCheckpointInterval sets how often connected cursors' positions and
+// stats are saved.
+func WithCheckpointInterval(interval time.Duration) Option {
+	return func(s *settings) {
+		s.manager = append(s.manager, server.WithCheckpointInterval(interval))
+	}
+}
+
+// New starts a hub, failing if the default database can't be opened or the
+// store can't be reset. Settings without an option keep the standalone
+// server's defaults; unlike the server, the hub doesn't read them from the
+// environment. Close stops it.
+func New(opts ...Option) (*Manager, error) {
+	var s settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if s.store == nil {
+		store, err := database.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening database: %w", err)
+		}
+		s.store = store
+	}
+
+	// nobody is connected to a hub that is just starting
+	if err := s.store.ResetAllSessions(); err != nil {
+		return nil, fmt.Errorf("resetting sessions: %w", err)
+	}
+
+	return server.NewManager(&s.store, s.manager...), nil
+}
@@ -0,0 +1,104 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAuthenticatorRejectsConnection(t *testing.T) {
+	h, err := New(
+		WithStore(NopStore{}),
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithAuthenticator(func(r *http.Request) (string, error) {
+			return "", errors.New("no session cookie")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close(context.Background())
+
+	w := httptest.NewRecorder()
+	h.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws?room=lobby", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestAuthenticatorGuardsRoomChanges(t *testing.T) {
+	h, err := New(
+		WithStore(NopStore{}),
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithAuthenticator(func(r *http.Request) (string, error) {
+			return "", errors.New("no session cookie")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close(context.Background())
+
+	w := httptest.NewRecorder()
+	h.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/rooms/lobby/behaviour", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestAuthenticatedUsernameAdmitted(t *testing.T) {
+	h, err := New(
+		WithStore(NopStore{}),
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithAuthenticator(func(r *http.Request) (string, error) {
+			return "alice@example.com", nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close(context.Background())
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("expected the authenticator's username to be admitted: %v", err)
+	}
+	conn.Close()
+}
+
+func TestAdminToken(t *testing.T) {
+	for _, tc := range []struct {
+		opts []Option
+		want int
+	}{
+		{want: http.StatusForbidden},
+		{opts: []Option{WithAdminToken("secret")}, want: http.StatusOK},
+	} {
+		h, err := New(append(tc.opts, WithStore(NopStore{}), WithLogger(log.New(io.Discard, "", 0)))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.Handler().ServeHTTP(w, req)
+		h.Close(context.Background())
+
+		if w.Code != tc.want {
+			t.Errorf("expected %d, got %d", tc.want, w.Code)
+		}
+	}
+}
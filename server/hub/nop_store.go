@@ -0,0 +1,258 @@
+package hub
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned by NopStore for any record looked up by its key.
+var ErrNotFound = errors.New("not found")
+
+// NopStore is a Store that keeps nothing: lookups find nothing and writes are
+// discarded. Embed it to implement only the parts of Store the service wants
+// persisted.
+type NopStore struct{}
+
+var _ Store = NopStore{}
+
+func (NopStore) Health() map[string]string {
+	return map[string]string{"status": "up"}
+}
+
+func (NopStore) Close() error {
+	return nil
+}
+
+func (NopStore) GetUsers() ([]User, error) {
+	return nil, nil
+}
+
+func (NopStore) CreateUser(user User) error {
+	return nil
+}
+
+func (NopStore) UpdateUser(user User) error {
+	return nil
+}
+
+func (NopStore) GetUser(username string) (User, error) {
+	return User{}, ErrNotFound
+}
+
+func (NopStore) CreateSession(session Session) error {
+	return nil
+}
+
+func (NopStore) UpdateSession(sessionId uuid.UUID) error {
+	return nil
+}
+
+func (NopStore) UpdateSessionStats(sessionId uuid.UUID, stats SessionStats) error {
+	return nil
+}
+
+func (NopStore) GetLatestSession(username string) (Session, error) {
+	return Session{}, ErrNotFound
+}
+
+func (NopStore) GetUserSessions() ([]UserSession, error) {
+	return nil, nil
+}
+
+func (NopStore) ResetAllSessions() error {
+	return nil
+}
+
+func (NopStore) SaveLastPosition(position LastPosition) error {
+	return nil
+}
+
+func (NopStore) GetLastPosition(username, room string) (LastPosition, error) {
+	return LastPosition{}, ErrNotFound
+}
+
+func (NopStore) GetLastPositions(username string) ([]LastPosition, error) {
+	return nil, nil
+}
+
+func (NopStore) GetBlocks(blocker string) ([]Block, error) {
+	return nil, nil
+}
+
+func (NopStore) CreateBlock(block Block) error {
+	return nil
+}
+
+func (NopStore) DeleteBlock(blocker, blocked string) error {
+	return nil
+}
+
+func (NopStore) GetRoom(name string) (Room, error) {
+	return Room{}, ErrNotFound
+}
+
+func (NopStore) SaveRoom(room Room) error {
+	return nil
+}
+
+func (NopStore) GetObjects(room string) ([]Object, error) {
+	return nil, nil
+}
+
+func (NopStore) SaveObject(object Object) error {
+	return nil
+}
+
+func (NopStore) GetStrokes(room string) ([]Stroke, error) {
+	return nil, nil
+}
+
+func (NopStore) GetStroke(id uuid.UUID) (Stroke, error) {
+	return Stroke{}, ErrNotFound
+}
+
+func (NopStore) CreateStroke(stroke Stroke) error {
+	return nil
+}
+
+func (NopStore) DeleteStroke(id uuid.UUID) error {
+	return nil
+}
+
+func (NopStore) GetComments(room string) ([]Comment, error) {
+	return nil, nil
+}
+
+func (NopStore) GetComment(id uuid.UUID) (Comment, error) {
+	return Comment{}, ErrNotFound
+}
+
+func (NopStore) CreateComment(comment Comment) (Comment, error) {
+	return comment, nil
+}
+
+func (NopStore) UpdateComment(comment Comment) (Comment, error) {
+	return comment, nil
+}
+
+func (NopStore) IncrementReaction(room, emoji string) error {
+	return nil
+}
+
+func (NopStore) GetReactionCounts(room string) ([]ReactionCount, error) {
+	return nil, nil
+}
+
+func (NopStore) GetZones(room string) ([]Zone, error) {
+	return nil, nil
+}
+
+func (NopStore) GetZone(id uuid.UUID) (Zone, error) {
+	return Zone{}, ErrNotFound
+}
+
+func (NopStore) CreateZone(zone Zone) (Zone, error) {
+	return zone, nil
+}
+
+func (NopStore) DeleteZone(id uuid.UUID) error {
+	return nil
+}
+
+func (NopStore) RecordZoneVisit(zoneId uuid.UUID, dwell time.Duration) error {
+	return nil
+}
+
+func (NopStore) GetZoneStats(zoneId uuid.UUID) (ZoneStats, error) {
+	return ZoneStats{ZoneID: zoneId}, nil
+}
+
+func (NopStore) GetWebhooks() ([]Webhook, error) {
+	return nil, nil
+}
+
+func (NopStore) GetWebhook(id uuid.UUID) (Webhook, error) {
+	return Webhook{}, ErrNotFound
+}
+
+func (NopStore) CreateWebhook(webhook Webhook) (Webhook, error) {
+	return webhook, nil
+}
+
+func (NopStore) UpdateWebhook(webhook Webhook) error {
+	return nil
+}
+
+func (NopStore) DeleteWebhook(id uuid.UUID) error {
+	return nil
+}
+
+func (NopStore) LogWebhookDelivery(delivery WebhookDelivery) error {
+	return nil
+}
+
+func (NopStore) GetWebhookDeliveries(webhookId uuid.UUID, limit int) ([]WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (NopStore) CreateWebhookDeadLetter(letter WebhookDeadLetter) error {
+	return nil
+}
+
+func (NopStore) GetWebhookDeadLetters(webhookId uuid.UUID) ([]WebhookDeadLetter, error) {
+	return nil, nil
+}
+
+func (NopStore) GetBans() ([]Ban, error) {
+	return nil, nil
+}
+
+func (NopStore) CreateBan(ban Ban) (Ban, error) {
+	return ban, nil
+}
+
+func (NopStore) DeleteBan(id uuid.UUID) error {
+	return nil
+}
+
+func (NopStore) GetPolls(room string) ([]Poll, error) {
+	return nil, nil
+}
+
+func (NopStore) GetPoll(id uuid.UUID) (Poll, error) {
+	return Poll{}, ErrNotFound
+}
+
+func (NopStore) CreatePoll(poll Poll) (Poll, error) {
+	return poll, nil
+}
+
+func (NopStore) UpdatePoll(poll Poll) (Poll, error) {
+	return poll, nil
+}
+
+func (NopStore) GetPollVotes(pollId uuid.UUID) ([]PollVote, error) {
+	return nil, nil
+}
+
+func (NopStore) SavePollVote(vote PollVote) error {
+	return nil
+}
+
+func (NopStore) AddGameScore(game, room, username string, delta int64) error {
+	return nil
+}
+
+func (NopStore) GetGameScores(game, room string) ([]GameScore, error) {
+	return nil, nil
+}
+
+func (NopStore) GetUserStats(username string, since *time.Time) (UserStats, error) {
+	return UserStats{UserName: username}, nil
+}
+
+func (NopStore) GetLeaderboard(metric string, since *time.Time, limit int) ([]UserStats, error) {
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package hub
+
+import "github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+// Store persists the hub's users, sessions and room content. WithStore
+// accepts any implementation, such as one backed by the embedding service's
+// own database.
+type Store = database.Service
+
+// Records read and written through a Store.
+type (
+	User              = database.User
+	Session           = database.Session
+	UserSession       = database.UserSession
+	SessionStats      = database.SessionStats
+	UserStats         = database.UserStats
+	LastPosition      = database.LastPosition
+	Room              = database.Room
+	Object            = database.Object
+	Stroke            = database.Stroke
+	StrokePoint       = database.StrokePoint
+	Comment           = database.Comment
+	ReactionCount     = database.ReactionCount
+	Zone              = database.Zone
+	ZonePoint         = database.ZonePoint
+	ZoneStats         = database.ZoneStats
+	Poll              = database.Poll
+	PollOption        = database.PollOption
+	PollVote          = database.PollVote
+	GameScore         = database.GameScore
+	Webhook           = database.Webhook
+	WebhookDelivery   = database.WebhookDelivery
+	WebhookDeadLetter = database.WebhookDeadLetter
+	Block             = database.Block
+	Ban               = database.Ban
+)
@@ -0,0 +1,22 @@
+package database
+
+import "github.com/google/uuid"
+
+func (s *service) GetBans() ([]Ban, error) {
+	var bans []Ban
+	result := s.db.Order("created_at asc").Find(&bans)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return bans, nil
+}
+
+func (s *service) CreateBan(ban Ban) (Ban, error) {
+	result := s.db.Create(&ban)
+	return ban, result.Error
+}
+
+func (s *service) DeleteBan(id uuid.UUID) error {
+	result := s.db.Delete(&Ban{}, "id = ?", id)
+	return result.Error
+}
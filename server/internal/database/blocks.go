@@ -0,0 +1,23 @@
+package database
+
+import "gorm.io/gorm/clause"
+
+func (s *service) GetBlocks(blocker string) ([]Block, error) {
+	var blocks []Block
+	result := s.db.Where("blocker = ?", blocker).Order("created_at asc").Find(&blocks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return blocks, nil
+}
+
+// CreateBlock records the block, doing nothing if it already exists.
+func (s *service) CreateBlock(block Block) error {
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&block)
+	return result.Error
+}
+
+func (s *service) DeleteBlock(blocker, blocked string) error {
+	result := s.db.Delete(&Block{}, "blocker = ? AND blocked = ?", blocker, blocked)
+	return result.Error
+}
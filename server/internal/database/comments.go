@@ -0,0 +1,31 @@
+package database
+
+import "github.com/google/uuid"
+
+func (s *service) GetComments(room string) ([]Comment, error) {
+	var comments []Comment
+	result := s.db.Where("room = ?", room).Order("created_at asc").Find(&comments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return comments, nil
+}
+
+func (s *service) GetComment(id uuid.UUID) (Comment, error) {
+	var comment Comment
+	result := s.db.Where("id = ?", id).First(&comment)
+	if result.Error != nil {
+		return Comment{}, result.Error
+	}
+	return comment, nil
+}
+
+func (s *service) CreateComment(comment Comment) (Comment, error) {
+	result := s.db.Create(&comment)
+	return comment, result.Error
+}
+
+func (s *service) UpdateComment(comment Comment) (Comment, error) {
+	result := s.db.Save(&comment)
+	return comment, result.Error
+}
@@ -25,7 +25,6 @@ type Service interface {
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
 	Close() error
-	migrate() error
 
 	// GetUsers returns a list of Users.
 	GetUsers() ([]User, error)
@@ -36,8 +35,71 @@ type Service interface {
 
 	CreateSession(session Session) error
 	UpdateSession(sessionId uuid.UUID) error
+	UpdateSessionStats(sessionId uuid.UUID, stats SessionStats) error
 	GetLatestSession(username string) (Session, error)
+	GetUserSessions() ([]UserSession, error)
 	ResetAllSessions() error
+
+	SaveLastPosition(position LastPosition) error
+	GetLastPosition(username, room string) (LastPosition, error)
+	GetLastPositions(username string) ([]LastPosition, error)
+
+	GetBlocks(blocker string) ([]Block, error)
+	CreateBlock(block Block) error
+	DeleteBlock(blocker, blocked string) error
+
+	GetRoom(name string) (Room, error)
+	SaveRoom(room Room) error
+
+	GetObjects(room string) ([]Object, error)
+	SaveObject(object Object) error
+
+	GetStrokes(room string) ([]Stroke, error)
+	GetStroke(id uuid.UUID) (Stroke, error)
+	CreateStroke(stroke Stroke) error
+	DeleteStroke(id uuid.UUID) error
+
+	GetComments(room string) ([]Comment, error)
+	GetComment(id uuid.UUID) (Comment, error)
+	CreateComment(comment Comment) (Comment, error)
+	UpdateComment(comment Comment) (Comment, error)
+
+	IncrementReaction(room, emoji string) error
+	GetReactionCounts(room string) ([]ReactionCount, error)
+
+	GetZones(room string) ([]Zone, error)
+	GetZone(id uuid.UUID) (Zone, error)
+	CreateZone(zone Zone) (Zone, error)
+	DeleteZone(id uuid.UUID) error
+	RecordZoneVisit(zoneId uuid.UUID, dwell time.Duration) error
+	GetZoneStats(zoneId uuid.UUID) (ZoneStats, error)
+
+	GetWebhooks() ([]Webhook, error)
+	GetWebhook(id uuid.UUID) (Webhook, error)
+	CreateWebhook(webhook Webhook) (Webhook, error)
+	UpdateWebhook(webhook Webhook) error
+	DeleteWebhook(id uuid.UUID) error
+	LogWebhookDelivery(delivery WebhookDelivery) error
+	GetWebhookDeliveries(webhookId uuid.UUID, limit int) ([]WebhookDelivery, error)
+	CreateWebhookDeadLetter(letter WebhookDeadLetter) error
+	GetWebhookDeadLetters(webhookId uuid.UUID) ([]WebhookDeadLetter, error)
+
+	GetBans() ([]Ban, error)
+	CreateBan(ban Ban) (Ban, error)
+	DeleteBan(id uuid.UUID) error
+
+	GetPolls(room string) ([]Poll, error)
+	GetPoll(id uuid.UUID) (Poll, error)
+	CreatePoll(poll Poll) (Poll, error)
+	UpdatePoll(poll Poll) (Poll, error)
+	GetPollVotes(pollId uuid.UUID) ([]PollVote, error)
+	SavePollVote(vote PollVote) error
+
+	AddGameScore(game, room, username string, delta int64) error
+	GetGameScores(game, room string) ([]GameScore, error)
+
+	GetUserStats(username string, since *time.Time) (UserStats, error)
+	GetLeaderboard(metric string, since *time.Time, limit int) ([]UserStats, error)
 }
 
 type service struct {
@@ -55,15 +117,25 @@ var (
 )
 
 func New() Service {
+	s, err := Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
+
+// Open connects to the database configured by the DB_* variables like New,
+// but returns the error instead of exiting when the connection fails.
+func Open() (Service, error) {
 	fmt.Println("Creating new database connection...")
 	// Reuse Connection
 	if dbInstance != nil {
-		return dbInstance
+		return dbInstance, nil
 	}
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
 	db, err := gorm.Open(postgres.Open(connStr), &gorm.Config{})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	dbInstance = &service{
@@ -74,7 +146,7 @@ func New() Service {
 
 	fmt.Println("Connected to database:", database)
 
-	return dbInstance
+	return dbInstance, nil
 }
 
 // Health checks the health of the database connection by pinging the database.
@@ -182,7 +254,23 @@ func (s *service) CreateSession(session Session) error {
 }
 
 func (s *service) UpdateSession(sessionId uuid.UUID) error {
-	result := s.db.Model(&Session{}).Where("id = ?", sessionId).Update("is_active", false)
+	result := s.db.Model(&Session{}).Where("id = ?", sessionId).Updates(map[string]interface{}{
+		"is_active": false,
+		"ended_at":  time.Now(),
+	})
+	return result.Error
+}
+
+func (s *service) UpdateSessionStats(sessionId uuid.UUID, stats SessionStats) error {
+	result := s.db.Model(&Session{}).Where("id = ?", sessionId).Updates(map[string]interface{}{
+		"position_updates": stats.PositionUpdates,
+		"pointer_downs":    stats.PointerDowns,
+		"pointer_ups":      stats.PointerUps,
+		"clicks":           stats.Clicks,
+		"drags":            stats.Drags,
+		"distance":         stats.Distance,
+		"top_speed":        stats.TopSpeed,
+	})
 	return result.Error
 }
 
@@ -196,7 +284,67 @@ func (s *service) GetLatestSession(username string) (Session, error) {
 	return session, nil
 }
 
+// UserSession is a user with the ID and start of their latest session, which
+// are nil if they have none.
+type UserSession struct {
+	User
+	SessionID        *uuid.UUID `gorm:"column:session_id"`
+	SessionCreatedAt *time.Time `gorm:"column:session_created_at"`
+}
+
+// GetUserSessions returns every user with their latest session in one query.
+func (s *service) GetUserSessions() ([]UserSession, error) {
+	var users []UserSession
+	result := s.db.Model(&User{}).
+		Select("users.name, users.color, users.mood, latest.id AS session_id, latest.created_at AS session_created_at").
+		Joins("LEFT JOIN LATERAL (SELECT id, created_at FROM sessions WHERE sessions.user_name = users.name ORDER BY created_at DESC LIMIT 1) latest ON true").
+		Scan(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return users, nil
+}
+
 func (s *service) ResetAllSessions() error {
 	result := s.db.Model(&Session{}).Update("is_active", false)
 	return result.Error
 }
+
+func (s *service) SaveLastPosition(position LastPosition) error {
+	result := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&position)
+	return result.Error
+}
+
+func (s *service) GetLastPosition(username, room string) (LastPosition, error) {
+	var position LastPosition
+	result := s.db.Where("user_name = ? AND room = ?", username, room).First(&position)
+
+	if result.Error != nil {
+		return LastPosition{}, result.Error
+	}
+	return position, nil
+}
+
+func (s *service) GetRoom(name string) (Room, error) {
+	var room Room
+	result := s.db.Where("name = ?", name).First(&room)
+
+	if result.Error != nil {
+		return Room{}, result.Error
+	}
+	return room, nil
+}
+
+func (s *service) SaveRoom(room Room) error {
+	result := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&room)
+	return result.Error
+}
+
+func (s *service) GetLastPositions(username string) ([]LastPosition, error) {
+	var positions []LastPosition
+	result := s.db.Where("user_name = ?", username).Order("updated_at desc").Find(&positions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return positions, nil
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *service) AddGameScore(game, room, username string, delta int64) error {
+	result := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "game"}, {Name: "room"}, {Name: "user_name"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"score":      gorm.Expr("game_scores.score + ?", delta),
+			"updated_at": gorm.Expr("NOW()"),
+		}),
+	}).Create(&GameScore{Game: game, Room: room, UserName: username, Score: delta})
+	return result.Error
+}
+
+func (s *service) GetGameScores(game, room string) ([]GameScore, error) {
+	var scores []GameScore
+	query := s.db.Where("game = ?", game)
+	if room != "" {
+		query = query.Where("room = ?", room)
+	}
+	result := query.Order("score desc").Find(&scores)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return scores, nil
+}
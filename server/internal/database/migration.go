@@ -9,6 +9,22 @@ func (s *service) migrate() error {
 
 	s.db.AutoMigrate(&User{})
 	s.db.AutoMigrate(&Session{})
+	s.db.AutoMigrate(&Object{})
+	s.db.AutoMigrate(&Stroke{})
+	s.db.AutoMigrate(&Comment{})
+	s.db.AutoMigrate(&ReactionCount{})
+	s.db.AutoMigrate(&Zone{})
+	s.db.AutoMigrate(&ZoneStats{})
+	s.db.AutoMigrate(&Webhook{})
+	s.db.AutoMigrate(&WebhookDelivery{})
+	s.db.AutoMigrate(&WebhookDeadLetter{})
+	s.db.AutoMigrate(&Poll{})
+	s.db.AutoMigrate(&PollVote{})
+	s.db.AutoMigrate(&GameScore{})
+	s.db.AutoMigrate(&LastPosition{})
+	s.db.AutoMigrate(&Room{})
+	s.db.AutoMigrate(&Ban{})
+	s.db.AutoMigrate(&Block{})
 
 	return nil
 }
@@ -0,0 +1,25 @@
+package database
+
+import "gorm.io/gorm/clause"
+
+func (s *service) GetObjects(room string) ([]Object, error) {
+	var objects []Object
+	result := s.db.Where("room = ?", room).Order("seq asc").Find(&objects)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return objects, nil
+}
+
+// SaveObject upserts the object unless a newer sequence is already stored, so
+// saves that reach the database out of order keep the latest state.
+func (s *service) SaveObject(object Object) error {
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "x", "y", "text", "color", "seq", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "objects.seq < excluded.seq"},
+		}},
+	}).Create(&object)
+	return result.Error
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+func (s *service) GetPolls(room string) ([]Poll, error) {
+	var polls []Poll
+	result := s.db.Where("room = ?", room).Order("created_at asc").Find(&polls)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return polls, nil
+}
+
+func (s *service) GetPoll(id uuid.UUID) (Poll, error) {
+	var poll Poll
+	result := s.db.Where("id = ?", id).First(&poll)
+	if result.Error != nil {
+		return Poll{}, result.Error
+	}
+	return poll, nil
+}
+
+func (s *service) CreatePoll(poll Poll) (Poll, error) {
+	result := s.db.Create(&poll)
+	return poll, result.Error
+}
+
+func (s *service) UpdatePoll(poll Poll) (Poll, error) {
+	result := s.db.Save(&poll)
+	return poll, result.Error
+}
+
+func (s *service) GetPollVotes(pollId uuid.UUID) ([]PollVote, error) {
+	var votes []PollVote
+	result := s.db.Where("poll_id = ?", pollId).Find(&votes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return votes, nil
+}
+
+func (s *service) SavePollVote(vote PollVote) error {
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "poll_id"}, {Name: "user_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"zone_id", "updated_at"}),
+	}).Create(&vote)
+	return result.Error
+}
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *service) IncrementReaction(room, emoji string) error {
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "room"}, {Name: "emoji"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("reaction_counts.count + 1")}),
+	}).Create(&ReactionCount{Room: room, Emoji: emoji, Count: 1})
+	return result.Error
+}
+
+func (s *service) GetReactionCounts(room string) ([]ReactionCount, error) {
+	var counts []ReactionCount
+	result := s.db.Where("room = ?", room).Order("count desc").Find(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return counts, nil
+}
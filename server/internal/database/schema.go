@@ -13,9 +13,192 @@ type User struct {
 }
 
 type Session struct {
+	ID        uuid.UUID    `gorm:"column:id;primaryKey"`
+	CreatedAt *time.Time   `gorm:"column:created_at;autoCreateTime"`
+	EndedAt   *time.Time   `gorm:"column:ended_at"`
+	IsActive  bool         `gorm:"column:is_active;default:true"`
+	UserName  string       `gorm:"column:user_name"`
+	Room      string       `gorm:"column:room;default:lobby"`
+	Stats     SessionStats `gorm:"embedded"`
+	User      User
+}
+
+type SessionStats struct {
+	PositionUpdates int `gorm:"column:position_updates;default:0"`
+	PointerDowns    int `gorm:"column:pointer_downs;default:0"`
+	PointerUps      int `gorm:"column:pointer_ups;default:0"`
+	Clicks          int `gorm:"column:clicks;default:0"`
+	Drags           int `gorm:"column:drags;default:0"`
+
+	Distance float64 `gorm:"column:distance;default:0"`
+	TopSpeed float64 `gorm:"column:top_speed;default:0"`
+}
+
+type Object struct {
 	ID        uuid.UUID  `gorm:"column:id;primaryKey"`
+	Room      string     `gorm:"column:room;index"`
+	Kind      string     `gorm:"column:kind"`
+	X         float64    `gorm:"column:x"`
+	Y         float64    `gorm:"column:y"`
+	Text      string     `gorm:"column:text"`
+	Color     int        `gorm:"column:color;default:0"`
+	Seq       int64      `gorm:"column:seq"`
+	CreatedBy string     `gorm:"column:created_by"`
 	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime"`
-	IsActive  bool       `gorm:"column:is_active;default:true"`
-	UserName  string     `gorm:"column:user_name"`
-	User      User
+	UpdatedAt *time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type Stroke struct {
+	ID        uuid.UUID     `gorm:"column:id;primaryKey"`
+	Room      string        `gorm:"column:room;index"`
+	Author    string        `gorm:"column:author"`
+	Color     int           `gorm:"column:color;default:0"`
+	Width     float64       `gorm:"column:width;default:2"`
+	Points    []StrokePoint `gorm:"column:points;serializer:json"`
+	CreatedAt *time.Time    `gorm:"column:created_at;autoCreateTime"`
+}
+
+type StrokePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Comment struct {
+	ID        uuid.UUID  `gorm:"column:id;primaryKey"`
+	Room      string     `gorm:"column:room;index"`
+	Author    string     `gorm:"column:author"`
+	X         float64    `gorm:"column:x"`
+	Y         float64    `gorm:"column:y"`
+	Text      string     `gorm:"column:text"`
+	Resolved  bool       `gorm:"column:resolved;default:false"`
+	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt *time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type ReactionCount struct {
+	Room  string `gorm:"column:room;primaryKey"`
+	Emoji string `gorm:"column:emoji;primaryKey"`
+	Count int64  `gorm:"column:count;default:0"`
+}
+
+type Zone struct {
+	ID        uuid.UUID   `gorm:"column:id;primaryKey"`
+	Room      string      `gorm:"column:room;index"`
+	Name      string      `gorm:"column:name"`
+	Shape     string      `gorm:"column:shape"`
+	Points    []ZonePoint `gorm:"column:points;serializer:json"`
+	CreatedAt *time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+type ZonePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type ZoneStats struct {
+	ZoneID     uuid.UUID `gorm:"column:zone_id;primaryKey"`
+	Visits     int64     `gorm:"column:visits;default:0"`
+	TotalDwell int64     `gorm:"column:total_dwell_ms;default:0"`
+	MaxDwell   int64     `gorm:"column:max_dwell_ms;default:0"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID  `gorm:"column:id;primaryKey"`
+	URL       string     `gorm:"column:url"`
+	Events    []string   `gorm:"column:events;serializer:json"`
+	Secret    string     `gorm:"column:secret"`
+	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// WebhookDelivery logs one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID         uuid.UUID  `gorm:"column:id;primaryKey"`
+	DeliveryID uuid.UUID  `gorm:"column:delivery_id;index"`
+	WebhookID  uuid.UUID  `gorm:"column:webhook_id;index"`
+	Event      string     `gorm:"column:event"`
+	Attempt    int        `gorm:"column:attempt"`
+	StatusCode int        `gorm:"column:status_code"`
+	Error      string     `gorm:"column:error"`
+	DurationMs int64      `gorm:"column:duration_ms"`
+	CreatedAt  *time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// WebhookDeadLetter keeps an event that could not be delivered after every
+// attempt, so it can be inspected or replayed.
+type WebhookDeadLetter struct {
+	ID        uuid.UUID  `gorm:"column:id;primaryKey"`
+	WebhookID uuid.UUID  `gorm:"column:webhook_id;index"`
+	Event     string     `gorm:"column:event"`
+	Payload   []byte     `gorm:"column:payload"`
+	Attempts  int        `gorm:"column:attempts"`
+	LastError string     `gorm:"column:last_error"`
+	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// Block hides the blocked user's cursor and messages from the blocker.
+type Block struct {
+	Blocker   string     `gorm:"column:blocker;primaryKey"`
+	Blocked   string     `gorm:"column:blocked;primaryKey"`
+	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// Ban keeps matching clients from connecting. Kind is one of "username", "ip"
+// or "cidr" and Value holds the username, address or range.
+type Ban struct {
+	ID        uuid.UUID  `gorm:"column:id;primaryKey"`
+	Kind      string     `gorm:"column:kind"`
+	Value     string     `gorm:"column:value"`
+	Reason    string     `gorm:"column:reason"`
+	CreatedBy string     `gorm:"column:created_by"`
+	CreatedAt *time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+type Poll struct {
+	ID          uuid.UUID    `gorm:"column:id;primaryKey"`
+	Room        string       `gorm:"column:room;index"`
+	Question    string       `gorm:"column:question"`
+	Facilitator string       `gorm:"column:facilitator"`
+	DwellMs     int64        `gorm:"column:dwell_ms"`
+	Options     []PollOption `gorm:"column:options;serializer:json"`
+	Closed      bool         `gorm:"column:closed;default:false"`
+	CreatedAt   *time.Time   `gorm:"column:created_at;autoCreateTime"`
+}
+
+type PollOption struct {
+	Label  string    `json:"label"`
+	ZoneID uuid.UUID `json:"zone_id"`
+}
+
+type PollVote struct {
+	PollID    uuid.UUID  `gorm:"column:poll_id;primaryKey"`
+	UserName  string     `gorm:"column:user_name;primaryKey"`
+	ZoneID    uuid.UUID  `gorm:"column:zone_id"`
+	UpdatedAt *time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type GameScore struct {
+	Game      string     `gorm:"column:game;primaryKey"`
+	Room      string     `gorm:"column:room;primaryKey"`
+	UserName  string     `gorm:"column:user_name;primaryKey"`
+	Score     int64      `gorm:"column:score;default:0"`
+	UpdatedAt *time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type LastPosition struct {
+	UserName  string     `gorm:"column:user_name;primaryKey"`
+	Room      string     `gorm:"column:room;primaryKey"`
+	X         float64    `gorm:"column:x"`
+	Y         float64    `gorm:"column:y"`
+	Vx        float64    `gorm:"column:vx"`
+	Vy        float64    `gorm:"column:vy"`
+	Spd       float64    `gorm:"column:spd"`
+	Acc       float64    `gorm:"column:acc"`
+	Ang       float64    `gorm:"column:ang"`
+	UpdatedAt *time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type Room struct {
+	Name         string     `gorm:"column:name;primaryKey"`
+	TrailSeconds int        `gorm:"column:trail_seconds"`
+	UpdatedAt    *time.Time `gorm:"column:updated_at;autoUpdateTime"`
 }
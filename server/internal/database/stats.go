@@ -0,0 +1,62 @@
+package database
+
+import (
+	"errors"
+	"time"
+)
+
+// UserStats aggregates a user's sessions. TimeOnline is in seconds.
+type UserStats struct {
+	UserName   string  `gorm:"column:user_name"`
+	Sessions   int64   `gorm:"column:sessions"`
+	TimeOnline float64 `gorm:"column:time_online"`
+	Distance   float64 `gorm:"column:distance"`
+	TopSpeed   float64 `gorm:"column:top_speed"`
+}
+
+var ErrUnknownMetric = errors.New("unknown leaderboard metric")
+
+// LeaderboardMetrics maps the metric names accepted by GetLeaderboard to the
+// aggregate column they are ranked by.
+var LeaderboardMetrics = map[string]string{
+	"sessions":    "sessions",
+	"time_online": "time_online",
+	"distance":    "distance",
+	"top_speed":   "top_speed",
+}
+
+// Sessions that were still active when the server last stopped are reset
+// without an end time, so they count as zero time online.
+const userStatsSelect = `user_name,
+	COUNT(*) AS sessions,
+	COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(ended_at, CASE WHEN is_active THEN NOW() ELSE created_at END) - created_at))), 0) AS time_online,
+	COALESCE(SUM(distance), 0) AS distance,
+	COALESCE(MAX(top_speed), 0) AS top_speed`
+
+func (s *service) GetUserStats(username string, since *time.Time) (UserStats, error) {
+	stats := UserStats{UserName: username}
+	query := s.db.Model(&Session{}).Select(userStatsSelect).Where("user_name = ?", username)
+	if since != nil {
+		query = query.Where("created_at >= ?", *since)
+	}
+	result := query.Group("user_name").Scan(&stats)
+	return stats, result.Error
+}
+
+func (s *service) GetLeaderboard(metric string, since *time.Time, limit int) ([]UserStats, error) {
+	column, ok := LeaderboardMetrics[metric]
+	if !ok {
+		return nil, ErrUnknownMetric
+	}
+
+	var stats []UserStats
+	query := s.db.Model(&Session{}).Select(userStatsSelect)
+	if since != nil {
+		query = query.Where("created_at >= ?", *since)
+	}
+	result := query.Group("user_name").Order(column + " desc").Limit(limit).Scan(&stats)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return stats, nil
+}
@@ -0,0 +1,31 @@
+package database
+
+import "github.com/google/uuid"
+
+func (s *service) GetStrokes(room string) ([]Stroke, error) {
+	var strokes []Stroke
+	result := s.db.Where("room = ?", room).Order("created_at asc").Find(&strokes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return strokes, nil
+}
+
+func (s *service) GetStroke(id uuid.UUID) (Stroke, error) {
+	var stroke Stroke
+	result := s.db.Where("id = ?", id).First(&stroke)
+	if result.Error != nil {
+		return Stroke{}, result.Error
+	}
+	return stroke, nil
+}
+
+func (s *service) CreateStroke(stroke Stroke) error {
+	result := s.db.Create(&stroke)
+	return result.Error
+}
+
+func (s *service) DeleteStroke(id uuid.UUID) error {
+	result := s.db.Delete(&Stroke{}, "id = ?", id)
+	return result.Error
+}
@@ -0,0 +1,65 @@
+package database
+
+import "github.com/google/uuid"
+
+func (s *service) GetWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	result := s.db.Order("created_at asc").Find(&webhooks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return webhooks, nil
+}
+
+func (s *service) GetWebhook(id uuid.UUID) (Webhook, error) {
+	var webhook Webhook
+	result := s.db.Where("id = ?", id).First(&webhook)
+	if result.Error != nil {
+		return Webhook{}, result.Error
+	}
+	return webhook, nil
+}
+
+func (s *service) CreateWebhook(webhook Webhook) (Webhook, error) {
+	result := s.db.Create(&webhook)
+	return webhook, result.Error
+}
+
+func (s *service) UpdateWebhook(webhook Webhook) error {
+	result := s.db.Save(&webhook)
+	return result.Error
+}
+
+func (s *service) DeleteWebhook(id uuid.UUID) error {
+	result := s.db.Delete(&Webhook{}, "id = ?", id)
+	return result.Error
+}
+
+func (s *service) LogWebhookDelivery(delivery WebhookDelivery) error {
+	result := s.db.Create(&delivery)
+	return result.Error
+}
+
+// GetWebhookDeliveries returns the most recent delivery attempts first.
+func (s *service) GetWebhookDeliveries(webhookId uuid.UUID, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	result := s.db.Where("webhook_id = ?", webhookId).Order("created_at desc").Limit(limit).Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deliveries, nil
+}
+
+func (s *service) CreateWebhookDeadLetter(letter WebhookDeadLetter) error {
+	result := s.db.Create(&letter)
+	return result.Error
+}
+
+func (s *service) GetWebhookDeadLetters(webhookId uuid.UUID) ([]WebhookDeadLetter, error) {
+	var letters []WebhookDeadLetter
+	result := s.db.Where("webhook_id = ?", webhookId).Order("created_at desc").Find(&letters)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return letters, nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *service) GetZones(room string) ([]Zone, error) {
+	var zones []Zone
+	result := s.db.Where("room = ?", room).Order("created_at asc").Find(&zones)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return zones, nil
+}
+
+func (s *service) GetZone(id uuid.UUID) (Zone, error) {
+	var zone Zone
+	result := s.db.Where("id = ?", id).First(&zone)
+	if result.Error != nil {
+		return Zone{}, result.Error
+	}
+	return zone, nil
+}
+
+func (s *service) CreateZone(zone Zone) (Zone, error) {
+	result := s.db.Create(&zone)
+	return zone, result.Error
+}
+
+func (s *service) DeleteZone(id uuid.UUID) error {
+	result := s.db.Delete(&Zone{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	result = s.db.Delete(&ZoneStats{}, "zone_id = ?", id)
+	return result.Error
+}
+
+func (s *service) RecordZoneVisit(zoneId uuid.UUID, dwell time.Duration) error {
+	ms := dwell.Milliseconds()
+	result := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "zone_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"visits":         gorm.Expr("zone_stats.visits + 1"),
+			"total_dwell_ms": gorm.Expr("zone_stats.total_dwell_ms + ?", ms),
+			"max_dwell_ms":   gorm.Expr("GREATEST(zone_stats.max_dwell_ms, ?)", ms),
+		}),
+	}).Create(&ZoneStats{ZoneID: zoneId, Visits: 1, TotalDwell: ms, MaxDwell: ms})
+	return result.Error
+}
+
+func (s *service) GetZoneStats(zoneId uuid.UUID) (ZoneStats, error) {
+	var stats ZoneStats
+	result := s.db.Where("zone_id = ?", zoneId).First(&stats)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return ZoneStats{ZoneID: zoneId}, nil
+	}
+	if result.Error != nil {
+		return ZoneStats{}, result.Error
+	}
+	return stats, nil
+}
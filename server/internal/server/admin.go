@@ -0,0 +1,303 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	EventMuted = "muted"
+
+	// maxCloseReason is the most a close frame can carry after its status
+	// code, in bytes
+	maxCloseReason = 123
+)
+
+var errMuted = errors.New("client is muted")
+
+// mutedEvents are the events a muted client may still send. It can move its
+// cursor and choose what it looks at, but nothing it sends reaches others as
+// an event of its own.
+var mutedEvents = map[string]bool{
+	EventUpdatePosition: true,
+	EventUpdateViewport: true,
+	EventFollow:         true,
+	EventUnfollow:       true,
+}
+
+// ConnectionInfo describes a live connection for the admin API.
+type ConnectionInfo struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	Room        string    `json:"room"`
+	Spectator   bool      `json:"spectator"`
+	Transport   string    `json:"transport"`
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"user_agent"`
+	ConnectedAt time.Time `json:"connected_at"`
+	MessagesIn  int64     `json:"messages_in"`
+	MessagesOut int64     `json:"messages_out"`
+	InRate      float64   `json:"in_rate"`
+	OutRate     float64   `json:"out_rate"`
+	QueueDepth  int       `json:"queue_depth"`
+	Muted       bool      `json:"muted"`
+	MuteReason  string    `json:"mute_reason,omitempty"`
+	State       *State    `json:"state,omitempty"`
+	Viewport    *Viewport `json:"viewport,omitempty"`
+}
+
+type KickRequest struct {
+	Reason string `json:"reason"`
+}
+
+type MuteRequest struct {
+	Muted  *bool  `json:"muted"`
+	Reason string `json:"reason"`
+}
+
+type Muted struct {
+	Muted  bool   `json:"muted"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// connectionInfo reports on a client, with message rates averaged over the
+// life of the connection. Callers must hold at least the manager read lock.
+func (c *Client) connectionInfo(now time.Time) ConnectionInfo {
+	in, out := c.messagesIn.Load(), c.messagesOut.Load()
+	elapsed := now.Sub(c.connectedAt).Seconds()
+
+	info := ConnectionInfo{
+		ID:          c.id.String(),
+		Username:    c.username,
+		Room:        c.room,
+		Spectator:   c.spectator,
+		Transport:   transportName(c.conn),
+		IP:          c.ip,
+		UserAgent:   c.userAgent,
+		ConnectedAt: c.connectedAt,
+		MessagesIn:  in,
+		MessagesOut: out,
+		QueueDepth:  len(c.egress),
+		Muted:       c.muted,
+		MuteReason:  c.muteReason,
+		Viewport:    c.viewport,
+	}
+	if elapsed > 0 {
+		info.InRate = float64(in) / elapsed
+		info.OutRate = float64(out) / elapsed
+	}
+	if !c.spectator {
+		state := c.state
+		info.State = &state
+	}
+	return info
+}
+
+func (c *Client) isMuted() bool {
+	c.manager.RLock()
+	defer c.manager.RUnlock()
+	return c.muted
+}
+
+func transportName(t transport) string {
+	switch t.(type) {
+	case *sseTransport:
+		return "sse"
+	case *virtualTransport:
+		return "virtual"
+	}
+	return "websocket"
+}
+
+// connection finds a live client or spectator. Callers must hold at least the
+// manager read lock.
+func (m *Manager) connection(id uuid.UUID) *Client {
+	for _, list := range []ClientList{m.Clients, m.spectators} {
+		for client := range list {
+			if client.id == id {
+				return client
+			}
+		}
+	}
+	return nil
+}
+
+// kick closes the client's connection, sending the reason in the close frame.
+// The read loop then fails and the client is removed as usual.
+func (c *Client) kick(reason string) error {
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, truncateReason(reason, maxCloseReason))
+	err := c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	c.conn.Close()
+	return err
+}
+
+// truncateReason shortens s to at most n bytes without splitting a character.
+func truncateReason(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// adminAuth only lets requests through that carry the admin token as a bearer
+// token. Without a token configured the admin API is disabled.
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API is disabled"})
+			return
+		}
+
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) registerAdminRoutes(r gin.IRouter) {
+	admin := r.Group("/admin", adminAuth(s.manager.adminToken))
+
+	admin.GET("/clients", s.getConnectionsHandler)
+
+	admin.GET("/clients/:id", s.getConnectionHandler)
+
+	admin.POST("/clients/:id/kick", s.kickConnectionHandler)
+
+	admin.POST("/clients/:id/mute", s.muteConnectionHandler)
+
+	admin.GET("/bans", s.getBansHandler)
+
+	admin.POST("/bans", s.createBanHandler)
+
+	admin.DELETE("/bans/:id", s.deleteBanHandler)
+
+	admin.GET("/webhooks", s.getWebhooksHandler)
+
+	admin.POST("/webhooks", s.createWebhookHandler)
+
+	admin.DELETE("/webhooks/:id", s.deleteWebhookHandler)
+
+	admin.POST("/webhooks/:id/secret", s.rotateWebhookSecretHandler)
+
+	admin.GET("/webhooks/:id/deliveries", s.getWebhookDeliveriesHandler)
+
+	admin.GET("/webhooks/:id/dead-letters", s.getWebhookDeadLettersHandler)
+}
+
+func (s *Server) getConnectionsHandler(c *gin.Context) {
+	room := c.Query("room")
+	now := time.Now()
+
+	s.manager.RLock()
+	connections := []ConnectionInfo{}
+	for _, list := range []ClientList{s.manager.Clients, s.manager.spectators} {
+		for client := range list {
+			if room == "" || client.room == room {
+				connections = append(connections, client.connectionInfo(now))
+			}
+		}
+	}
+	s.manager.RUnlock()
+
+	c.JSON(http.StatusOK, connections)
+}
+
+// findConnection looks up the client named by the id parameter, writing an
+// error response if it isn't connected.
+func (s *Server) findConnection(c *gin.Context) (*Client, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	s.manager.RLock()
+	client := s.manager.connection(id)
+	s.manager.RUnlock()
+
+	if client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client not connected"})
+		return nil, false
+	}
+	return client, true
+}
+
+func (s *Server) getConnectionHandler(c *gin.Context) {
+	client, ok := s.findConnection(c)
+	if !ok {
+		return
+	}
+
+	s.manager.RLock()
+	info := client.connectionInfo(time.Now())
+	s.manager.RUnlock()
+
+	c.JSON(http.StatusOK, info)
+}
+
+func (s *Server) kickConnectionHandler(c *gin.Context) {
+	var req KickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := s.findConnection(c)
+	if !ok {
+		return
+	}
+
+	if req.Reason == "" {
+		req.Reason = "kicked by an administrator"
+	}
+	if err := client.kick(req.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) muteConnectionHandler(c *gin.Context) {
+	var req MuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := s.findConnection(c)
+	if !ok {
+		return
+	}
+
+	muted := req.Muted == nil || *req.Muted
+
+	s.manager.Lock()
+	client.muted = muted
+	client.muteReason = ""
+	if muted {
+		client.muteReason = req.Reason
+	}
+	info := client.connectionInfo(time.Now())
+	s.manager.Unlock()
+
+	if event, err := NewEvent(EventMuted, Muted{Muted: muted, Reason: info.MuteReason}); err == nil {
+		send(event, client)
+	}
+
+	c.JSON(http.StatusOK, info)
+}
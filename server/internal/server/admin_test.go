@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuth(t *testing.T) {
+	r := gin.New()
+	r.GET("/admin", adminAuth("secret"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/disabled", adminAuth(""), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path, header string
+		want         int
+	}{
+		{"/admin", "Bearer secret", http.StatusOK},
+		{"/admin", "Bearer wrong", http.StatusUnauthorized},
+		{"/admin", "", http.StatusUnauthorized},
+		{"/disabled", "Bearer ", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Authorization", tt.header)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s with %q: got %d want %d", tt.path, tt.header, rr.Code, tt.want)
+		}
+	}
+}
+
+func TestGetConnectionsHandler(t *testing.T) {
+	m := newTestManager(t)
+	client := addTestClient(m, 0, 0)
+	client.username = "alice"
+	client.ip = "10.0.0.1"
+	client.messagesIn.Add(3)
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.GET("/admin/clients", s.getConnectionsHandler)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/admin/clients?room=lobby", nil))
+
+	var connections []ConnectionInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &connections); err != nil {
+		t.Fatal(err)
+	}
+	if len(connections) != 1 {
+		t.Fatalf("expected one connection, got %d", len(connections))
+	}
+	got := connections[0]
+	if got.ID != client.id.String() || got.Username != "alice" || got.IP != "10.0.0.1" || got.MessagesIn != 3 {
+		t.Errorf("unexpected connection info: %+v", got)
+	}
+}
+
+func TestMuteConnectionHandler(t *testing.T) {
+	m := newTestManager(t)
+	client := addTestClient(m, 0, 0)
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.POST("/admin/clients/:id/mute", s.muteConnectionHandler)
+
+	body := `{"muted":true,"reason":"spam"}`
+	req := httptest.NewRequest("POST", "/admin/clients/"+client.id.String()+"/mute", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	event := receive(t, client, EventMuted)
+	var muted Muted
+	if err := json.Unmarshal(event.Payload, &muted); err != nil {
+		t.Fatal(err)
+	}
+	if !muted.Muted || muted.Reason != "spam" {
+		t.Errorf("unexpected mute notice: %+v", muted)
+	}
+
+	click := Event{Type: EventClick, Payload: json.RawMessage(`{}`)}
+	if err := m.routeEvent(click, client); err != errMuted {
+		t.Errorf("expected muted client to be rejected, got %v", err)
+	}
+	viewport := Event{Type: EventUpdateViewport, Payload: json.RawMessage(`{"x":0,"y":0,"w":10,"h":10}`)}
+	if err := m.routeEvent(viewport, client); err != nil {
+		t.Errorf("expected muted client to update its viewport, got %v", err)
+	}
+	position := Event{Type: EventUpdatePosition, Payload: json.RawMessage(`{"x":5,"y":5,"delta":16}`)}
+	if err := m.routeEvent(position, client); err != nil {
+		t.Errorf("expected muted client to keep moving its cursor, got %v", err)
+	}
+}
+
+func TestTruncateReason(t *testing.T) {
+	long := strings.Repeat("é", 100)
+	reason := truncateReason(long, maxCloseReason)
+	if len(reason) > maxCloseReason || !utf8.ValidString(reason) {
+		t.Fatalf("expected at most %d bytes of valid UTF-8, got %d", maxCloseReason, len(reason))
+	}
+	if len(reason) != 122 {
+		t.Fatalf("expected to cut before the split character, got %d bytes", len(reason))
+	}
+	if truncateReason("spam", maxCloseReason) != "spam" {
+		t.Fatal("expected a short reason to be kept")
+	}
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	BanUsername = "username"
+	BanIP       = "ip"
+	BanCIDR     = "cidr"
+)
+
+var (
+	errInvalidBan      = errors.New("value is not a valid address for the ban kind")
+	errBansUnavailable = errors.New("bans could not be checked, try again later")
+)
+
+type Ban struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"`
+	Value     string     `json:"value"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+func newBan(b database.Ban) Ban {
+	return Ban{
+		ID:        b.ID,
+		Kind:      b.Kind,
+		Value:     b.Value,
+		Reason:    b.Reason,
+		CreatedBy: b.CreatedBy,
+		CreatedAt: b.CreatedAt,
+	}
+}
+
+type NewBanRequest struct {
+	Kind      string `json:"kind" binding:"required,oneof=username ip cidr"`
+	Value     string `json:"value" binding:"required"`
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by"`
+}
+
+// record checks the value parses for its kind and normalises it, so equal
+// addresses and ranges are always stored the same way.
+func (r NewBanRequest) record() (database.Ban, error) {
+	value := strings.TrimSpace(r.Value)
+	switch r.Kind {
+	case BanIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return database.Ban{}, errInvalidBan
+		}
+		value = ip.String()
+	case BanCIDR:
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return database.Ban{}, errInvalidBan
+		}
+		value = network.String()
+	}
+
+	return database.Ban{
+		ID:        uuid.New(),
+		Kind:      r.Kind,
+		Value:     value,
+		Reason:    r.Reason,
+		CreatedBy: r.CreatedBy,
+	}, nil
+}
+
+type banRule struct {
+	database.Ban
+	ip      net.IP
+	network *net.IPNet
+}
+
+func newBanRule(b database.Ban) banRule {
+	rule := banRule{Ban: b}
+	switch b.Kind {
+	case BanIP:
+		rule.ip = net.ParseIP(b.Value)
+	case BanCIDR:
+		_, rule.network, _ = net.ParseCIDR(b.Value)
+	}
+	return rule
+}
+
+func (r banRule) matches(username string, ip net.IP) bool {
+	switch r.Kind {
+	case BanUsername:
+		return username != "" && strings.EqualFold(r.Value, username)
+	case BanIP:
+		return ip != nil && r.ip.Equal(ip)
+	case BanCIDR:
+		return ip != nil && r.network != nil && r.network.Contains(ip)
+	}
+	return false
+}
+
+// banList caches the bans so connections can be checked without a query. Like
+// the webhooks it is reloaded whenever the bans change over REST.
+type banList struct {
+	db     database.Service
+	logger *log.Logger
+
+	mu     sync.RWMutex
+	rules  []banRule
+	loaded bool
+}
+
+func newBanList(db database.Service, logger *log.Logger) *banList {
+	return &banList{db: db, logger: logger}
+}
+
+func (l *banList) reload() error {
+	bans, err := l.db.GetBans()
+	if err != nil {
+		return err
+	}
+	l.set(bans)
+	return nil
+}
+
+func (l *banList) set(bans []database.Ban) {
+	rules := make([]banRule, 0, len(bans))
+	for _, ban := range bans {
+		rules = append(rules, newBanRule(ban))
+	}
+
+	l.mu.Lock()
+	l.rules = rules
+	l.loaded = true
+	l.mu.Unlock()
+}
+
+// match returns the first ban covering the username or IP address. If the
+// bans have never loaded it fails rather than let everyone through; once
+// loaded, a failed reload keeps the last good list.
+func (l *banList) match(username, addr string) (database.Ban, bool, error) {
+	if l == nil {
+		return database.Ban{}, false, nil
+	}
+
+	l.mu.RLock()
+	loaded := l.loaded
+	l.mu.RUnlock()
+
+	if !loaded {
+		if err := l.reload(); err != nil {
+			return database.Ban{}, false, err
+		}
+	}
+
+	ip := net.ParseIP(addr)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, rule := range l.rules {
+		if rule.matches(username, ip) {
+			return rule.Ban, true, nil
+		}
+	}
+	return database.Ban{}, false, nil
+}
+
+// enforceBans disconnects every client and spectator covered by a ban, so a
+// new ban takes effect straight away. It returns how many were kicked.
+func (m *Manager) enforceBans() int {
+	type banned struct {
+		client *Client
+		reason string
+	}
+
+	type connection struct {
+		client       *Client
+		username, ip string
+	}
+
+	// matching may load the bans from the database, so it happens outside
+	// the manager lock
+	m.RLock()
+	var connections []connection
+	for _, list := range []ClientList{m.Clients, m.spectators} {
+		for client := range list {
+			connections = append(connections, connection{client, client.username, client.ip})
+		}
+	}
+	m.RUnlock()
+
+	var kicks []banned
+	for _, conn := range connections {
+		if ban, ok, _ := m.bans.match(conn.username, conn.ip); ok {
+			kicks = append(kicks, banned{conn.client, banReason(ban)})
+		}
+	}
+
+	for _, kick := range kicks {
+		if err := kick.client.kick(kick.reason); err != nil {
+			m.logger.Printf("error kicking %s: %v", kick.client.username, err)
+		}
+	}
+	return len(kicks)
+}
+
+func banReason(ban database.Ban) string {
+	if ban.Reason == "" {
+		return "banned"
+	}
+	return "banned: " + ban.Reason
+}
+
+func (s *Server) getBansHandler(c *gin.Context) {
+	records, err := s.db.GetBans()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	bans := []Ban{}
+	for _, record := range records {
+		bans = append(bans, newBan(record))
+	}
+	c.JSON(http.StatusOK, bans)
+}
+
+func (s *Server) createBanHandler(c *gin.Context) {
+	var req NewBanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := req.record()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err = s.db.CreateBan(record)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.bans.reload(); err != nil {
+		s.manager.logger.Printf("error reloading bans: %v", err)
+	}
+	if kicked := s.manager.enforceBans(); kicked > 0 {
+		s.manager.logger.Printf("ban %s disconnected %d clients", record.ID, kicked)
+	}
+
+	c.JSON(http.StatusCreated, newBan(record))
+}
+
+func (s *Server) deleteBanHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.db.DeleteBan(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.bans.reload(); err != nil {
+		s.manager.logger.Printf("error reloading bans: %v", err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
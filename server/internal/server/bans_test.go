@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+)
+
+func TestBanListMatch(t *testing.T) {
+	l := &banList{}
+	l.set([]database.Ban{
+		{Kind: BanUsername, Value: "Mallory", Reason: "spam"},
+		{Kind: BanIP, Value: "192.0.2.7"},
+		{Kind: BanCIDR, Value: "198.51.100.0/24"},
+	})
+
+	tests := []struct {
+		username, ip string
+		banned       bool
+	}{
+		{"mallory", "203.0.113.1", true},
+		{"alice", "192.0.2.7", true},
+		{"alice", "198.51.100.42", true},
+		{"alice", "198.51.101.1", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if _, banned, _ := l.match(tt.username, tt.ip); banned != tt.banned {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.username, tt.ip, banned, tt.banned)
+		}
+	}
+
+	var disabled *banList
+	if _, banned, _ := disabled.match("mallory", ""); banned {
+		t.Errorf("expected a nil ban list to ban nobody")
+	}
+}
+
+func TestNewBanRequestRecord(t *testing.T) {
+	record, err := NewBanRequest{Kind: BanCIDR, Value: " 10.1.2.3/8 "}.record()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Value != "10.0.0.0/8" {
+		t.Errorf("expected the range to be normalised, got %q", record.Value)
+	}
+
+	if _, err := (NewBanRequest{Kind: BanIP, Value: "not-an-ip"}).record(); err != errInvalidBan {
+		t.Errorf("expected an invalid IP to be rejected, got %v", err)
+	}
+}
+
+type failingBans struct {
+	fakeDB
+}
+
+func (*failingBans) GetBans() ([]database.Ban, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestBanListFailsClosed(t *testing.T) {
+	l := newBanList(&failingBans{}, log.Default())
+	if _, _, err := l.match("alice", "192.0.2.1"); err == nil {
+		t.Fatal("expected an error when the bans have never loaded")
+	}
+
+	l.set([]database.Ban{{Kind: BanUsername, Value: "mallory"}})
+	if err := l.reload(); err == nil {
+		t.Fatal("expected the reload to fail")
+	}
+	if _, banned, err := l.match("mallory", ""); err != nil || !banned {
+		t.Fatalf("expected the last good bans to be kept, got %v, %v", banned, err)
+	}
+}
+
+func TestBanIgnoresForwardedFor(t *testing.T) {
+	m := newTestManager(t)
+	m.bans.set([]database.Ban{{Kind: BanIP, Value: "192.0.2.1"}})
+	r := m.Handler()
+
+	connect := func(remote, forwarded string) int {
+		req := httptest.NewRequest("GET", "/ws?username=alice", nil)
+		req.RemoteAddr = remote + ":4000"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := connect("192.0.2.1", "203.0.113.9"); code != http.StatusForbidden {
+		t.Errorf("expected a spoofed header not to hide a banned address, got %d", code)
+	}
+	if code := connect("203.0.113.9", "192.0.2.1"); code == http.StatusForbidden {
+		t.Errorf("expected an untrusted header not to ban someone else")
+	}
+}
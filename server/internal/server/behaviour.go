@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+)
+
+const (
+	EventGameState = "game_state"
+	EventGameEnd   = "game_end"
+)
+
+var (
+	errUnknownBehaviour = errors.New("unknown room behaviour")
+	errBehaviourRunning = errors.New("room is already running a behaviour")
+	errNoBehaviour      = errors.New("room is not running a behaviour")
+)
+
+// RoomBehaviour is a server-authoritative mode, such as a game, that a room
+// can run on top of cursor tracking. The manager calls its hooks with the
+// manager lock held, so they must not block or send to clients directly.
+// Join, Leave and Move report whether the behaviour's state changed, in which
+// case the new State is broadcast to the room and any Scores are persisted.
+type RoomBehaviour interface {
+	// Name identifies the behaviour, and is used to key persisted scores.
+	Name() string
+
+	Join(c *Client) bool
+	Leave(c *Client) bool
+
+	// Move is called after c's position changes, with the other clients in
+	// its room.
+	Move(c *Client, peers []*Client) bool
+
+	// State returns the snapshot sent to clients.
+	State() interface{}
+
+	// Scores returns and clears the score changes since the last call, keyed
+	// by username.
+	Scores() map[string]int64
+}
+
+// BehaviourFactory creates a fresh behaviour for a room.
+type BehaviourFactory func() RoomBehaviour
+
+type GameEnd struct {
+	Game string `json:"game"`
+	Room string `json:"room"`
+}
+
+type behaviourUpdate struct {
+	room   string
+	game   string
+	state  interface{}
+	scores map[string]int64
+}
+
+// RegisterBehaviour makes a behaviour available for rooms to run by name.
+func (m *Manager) RegisterBehaviour(name string, factory BehaviourFactory) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.behaviourFactories[name] = factory
+}
+
+// startBehaviour runs the named behaviour in a room, joining everyone already
+// connected to it.
+func (m *Manager) startBehaviour(room, name string) error {
+	m.Lock()
+	factory, ok := m.behaviourFactories[name]
+	if !ok {
+		m.Unlock()
+		return errUnknownBehaviour
+	}
+	if _, ok := m.behaviours[room]; ok {
+		m.Unlock()
+		return errBehaviourRunning
+	}
+
+	behaviour := factory()
+	m.behaviours[room] = behaviour
+	for client := range m.Clients {
+		if client.room == room {
+			behaviour.Join(client)
+		}
+	}
+	update := m.behaviourUpdate(room)
+	m.Unlock()
+
+	m.publishBehaviour(update)
+	return nil
+}
+
+func (m *Manager) stopBehaviour(room string) error {
+	m.Lock()
+	update := m.behaviourUpdate(room)
+	delete(m.behaviours, room)
+	m.Unlock()
+
+	if update.state == nil {
+		return errNoBehaviour
+	}
+
+	// persist any outstanding scores before announcing the end
+	m.persistScores(update)
+
+	event, err := NewEvent(EventGameEnd, GameEnd{Game: update.game, Room: room})
+	if err != nil {
+		return err
+	}
+	m.broadcast(room, event, nil)
+	return nil
+}
+
+// behaviourState returns the state of the behaviour running in a room.
+func (m *Manager) behaviourState(room string) (interface{}, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	behaviour, ok := m.behaviours[room]
+	if !ok {
+		return nil, false
+	}
+	return behaviour.State(), true
+}
+
+// applyBehaviour calls fn with the room's behaviour, if any, and returns an
+// update to publish when it reports a change. Callers must hold the manager
+// lock.
+func (m *Manager) applyBehaviour(room string, fn func(RoomBehaviour) bool) *behaviourUpdate {
+	behaviour, ok := m.behaviours[room]
+	if !ok || !fn(behaviour) {
+		return nil
+	}
+	return m.behaviourUpdate(room)
+}
+
+// behaviourUpdate snapshots the room's behaviour. Callers must hold the
+// manager lock.
+func (m *Manager) behaviourUpdate(room string) *behaviourUpdate {
+	behaviour, ok := m.behaviours[room]
+	if !ok {
+		return &behaviourUpdate{room: room}
+	}
+	return &behaviourUpdate{
+		room:   room,
+		game:   behaviour.Name(),
+		state:  behaviour.State(),
+		scores: behaviour.Scores(),
+	}
+}
+
+func (m *Manager) persistScores(update *behaviourUpdate) {
+	for username, delta := range update.scores {
+		if err := m.db.AddGameScore(update.game, update.room, username, delta); err != nil {
+			m.logger.Printf("error saving %s score: %v", update.game, err)
+		}
+	}
+}
+
+// publishBehaviour persists score changes and broadcasts the new state.
+func (m *Manager) publishBehaviour(update *behaviourUpdate) {
+	if update == nil || update.state == nil {
+		return
+	}
+
+	m.persistScores(update)
+
+	event, err := NewEvent(EventGameState, update.state)
+	if err != nil {
+		m.logger.Printf("failed to marshal %s state: %v", update.game, err)
+		return
+	}
+	m.broadcast(update.room, event, nil)
+}
+
+// roomPeers returns the other clients in c's room. Callers must hold the
+// manager lock.
+func (m *Manager) roomPeers(c *Client) []*Client {
+	var peers []*Client
+	for client := range m.Clients {
+		if client.room == c.room && client != c {
+			peers = append(peers, client)
+		}
+	}
+	return peers
+}
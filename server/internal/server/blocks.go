@@ -0,0 +1,153 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errBlockSelf = errors.New("cannot block yourself")
+
+type Block struct {
+	Blocker   string     `json:"blocker"`
+	Blocked   string     `json:"blocked"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+func newBlock(b database.Block) Block {
+	return Block{
+		Blocker:   b.Blocker,
+		Blocked:   b.Blocked,
+		CreatedAt: b.CreatedAt,
+	}
+}
+
+type BlockRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// loadBlocks returns the usernames the user has blocked.
+func (m *Manager) loadBlocks(username string) map[string]bool {
+	blocked := make(map[string]bool)
+	blocks, err := m.db.GetBlocks(username)
+	if err != nil {
+		m.logger.Printf("error loading blocks for %s: %v", username, err)
+		return blocked
+	}
+	for _, block := range blocks {
+		blocked[block.Blocked] = true
+	}
+	return blocked
+}
+
+// blocks reports whether the client has blocked the user. Callers must hold at
+// least the manager read lock.
+func (c *Client) blocks(username string) bool {
+	return c.blocked[username]
+}
+
+// unblocked wraps a broadcast filter so the event skips anyone who has blocked
+// the sender.
+func (m *Manager) unblocked(sender string, include func(*Client) bool) func(*Client) bool {
+	return func(client *Client) bool {
+		m.RLock()
+		blocked := client.blocks(sender)
+		m.RUnlock()
+		if blocked {
+			return false
+		}
+		return include == nil || include(client)
+	}
+}
+
+// setBlocked applies a block change to every connection of the blocker and
+// refreshes the cursors they see.
+func (m *Manager) setBlocked(blocker, blocked string, block bool) {
+	m.Lock()
+	rooms := make(map[string]bool)
+	for client := range m.Clients {
+		if client.username != blocker {
+			continue
+		}
+		if client.blocked == nil {
+			client.blocked = make(map[string]bool)
+		}
+		if block {
+			client.blocked[blocked] = true
+		} else {
+			delete(client.blocked, blocked)
+		}
+		rooms[client.room] = true
+	}
+	m.Unlock()
+
+	for room := range rooms {
+		m.broadcastRoomState(room)
+	}
+}
+
+func (s *Server) getBlocksHandler(c *gin.Context) {
+	records, err := s.db.GetBlocks(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	blocks := []Block{}
+	for _, record := range records {
+		blocks = append(blocks, newBlock(record))
+	}
+	c.JSON(http.StatusOK, blocks)
+}
+
+func (s *Server) createBlockHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Username == username {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errBlockSelf.Error()})
+		return
+	}
+
+	if _, err := s.db.GetUser(req.Username); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	block := database.Block{Blocker: username, Blocked: req.Username}
+	if err := s.db.CreateBlock(block); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.manager.setBlocked(username, req.Username, true)
+
+	c.JSON(http.StatusCreated, newBlock(block))
+}
+
+func (s *Server) deleteBlockHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.db.DeleteBlock(username, req.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.manager.setBlocked(username, req.Username, false)
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBlockedCursorsHidden(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	alice.username = "alice"
+	bob := addTestClient(m, 10, 10)
+	bob.username = "bob"
+
+	m.setBlocked("alice", "bob", true)
+
+	m.RLock()
+	visible := m.visibleClients(alice)
+	m.RUnlock()
+	if len(visible) != 1 || visible[0] != alice {
+		t.Fatalf("expected bob's cursor to be hidden from alice, got %d cursors", len(visible))
+	}
+
+	m.RLock()
+	visible = m.visibleClients(bob)
+	m.RUnlock()
+	if len(visible) != 2 {
+		t.Fatalf("expected a block to be one way, got %d cursors for bob", len(visible))
+	}
+
+	m.setBlocked("alice", "bob", false)
+
+	m.RLock()
+	visible = m.visibleClients(alice)
+	m.RUnlock()
+	if len(visible) != 2 {
+		t.Fatalf("expected bob's cursor back after unblocking, got %d cursors", len(visible))
+	}
+}
+
+func TestBlockedReactionsFiltered(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	alice.username = "alice"
+	bob := addTestClient(m, 10, 10)
+	bob.username = "bob"
+	m.setBlocked("alice", "bob", true)
+
+	event := Event{Type: EventReaction, Payload: json.RawMessage(`{"emoji":"🎉"}`)}
+	if err := React(event, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	receive(t, bob, EventReaction)
+	for {
+		select {
+		case event := <-alice.egress:
+			if event.Type == EventReaction {
+				t.Fatalf("expected alice not to see bob's reaction")
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestBlocksNeedOwnSession(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	alice.username, alice.token = "alice", "alice-token"
+	bob := addTestClient(m, 10, 10)
+	bob.username, bob.token = "bob", "bob-token"
+
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.GET("/users/:username/blocks", m.userAuth, s.getBlocksHandler)
+	list := func(token string) int {
+		req := httptest.NewRequest("GET", "/users/alice/blocks", nil)
+		if token != "" {
+			req.Header.Set(SessionTokenHeader, token)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := list(""); code != http.StatusUnauthorized {
+		t.Fatalf("expected a request without a session to be refused, got %d", code)
+	}
+	if code := list("bob-token"); code != http.StatusForbidden {
+		t.Fatalf("expected bob not to see alice's blocks, got %d", code)
+	}
+	if code := list(alice.token); code != http.StatusOK {
+		t.Fatalf("expected alice to see their blocks, got %d", code)
+	}
+
+	m.auth = func(r *http.Request) (string, error) { return "alice", nil }
+	if code := list(""); code != http.StatusOK {
+		t.Fatalf("expected the authenticator to identify alice, got %d", code)
+	}
+}
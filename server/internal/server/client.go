@@ -2,7 +2,11 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -10,6 +14,20 @@ import (
 
 type ClientList map[*Client]bool
 
+// egressBuffer is how many outgoing events can queue for a client before it is
+// considered too slow and disconnected.
+const egressBuffer = 256
+
+// transport carries a client's messages. It is satisfied by *websocket.Conn
+// and by the SSE fallback, so the manager and handlers don't need to know how
+// a client is connected.
+type transport interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	Close() error
+}
+
 type State struct {
 	X   float64 `json:"x"`
 	Y   float64 `json:"y"`
@@ -34,43 +52,178 @@ func NewState() State {
 
 type Client struct {
 	id       uuid.UUID
+	session  uuid.UUID
 	username string
+	room     string
 	color    int
 	mood     string
 	state    State
+	viewport *Viewport
+	trail    *trailBuffer
+
+	// counters persisted with the session
+	statsMu sync.Mutex
+	stats   database.SessionStats
+
+	// strokes being drawn, only touched by the read loop
+	strokes map[uuid.UUID]*database.Stroke
+
+	reactions *rateLimiter
+
+	// zones the client is in and when it entered them, guarded by the
+	// manager lock
+	zones map[uuid.UUID]time.Time
+
+	// read-only connections that receive broadcasts but have no cursor
+	spectator bool
+
+	// clients driven over the ingestion API, disconnected when no update
+	// arrives within the manager's virtualTTL
+	virtual    bool
+	lastUpdate time.Time
 
-	// websocket connection
-	conn    *websocket.Conn
+	// set once the client has disconnected and lingers as a ghost
+	disconnectedAt time.Time
+
+	// websocket or SSE connection
+	conn    transport
 	manager *Manager
 
-	// channels for communication
-	egress chan Event
+	// identifies the client's user over REST, sent in its session event
+	token string
+
+	// connection details for the admin API
+	ip          string
+	userAgent   string
+	connectedAt time.Time
+	messagesIn  atomic.Int64
+	messagesOut atomic.Int64
+
+	// usernames this client has blocked, guarded by the manager lock
+	blocked map[string]bool
+
+	// muted clients can only send events that affect themselves, guarded by
+	// the manager lock
+	muted      bool
+	muteReason string
+
+	// channels for communication, egress is closed once the client is
+	// removed so its write loop ends
+	egress       chan Event
+	egressMu     sync.Mutex
+	egressClosed bool
+	overflow     atomic.Bool
 }
 
-func NewClient(username string, conn *websocket.Conn, manager *Manager) *Client {
+func NewClient(username, room string, conn transport, manager *Manager) *Client {
 	id := uuid.New()
 	user, err := manager.db.GetUser(username)
 
 	if err != nil {
-		log.Printf("error getting user: %v", err)
+		manager.logger.Printf("error getting user: %v", err)
 	}
 
 	return &Client{
 		id:       id,
+		session:  uuid.New(),
 		username: username,
+		room:     room,
 		color:    user.Color,
 		mood:     user.Mood,
 		conn:     conn,
 		manager:  manager,
-		state:    NewState(),
+		state:    restoreState(manager.db, username, room),
+		blocked:  manager.loadBlocks(username),
+		trail:    newTrailBuffer(),
+		strokes:  make(map[uuid.UUID]*database.Stroke),
+
+		reactions: newRateLimiter(manager.reactionRate, manager.reactionBurst),
+		zones:     make(map[uuid.UUID]time.Time),
+
+		connectedAt: time.Now(),
+
+		egress: make(chan Event, egressBuffer),
+	}
+}
+
+// restoreState returns the user's last saved position in the room, so a
+// returning ghost reappears where it left. Motion is not restored since the
+// cursor is at rest when it reconnects.
+func restoreState(db database.Service, username, room string) State {
+	state := NewState()
+	position, err := db.GetLastPosition(username, room)
+	if err != nil {
+		return state
+	}
+	state.X = position.X
+	state.Y = position.Y
+	return state
+}
 
-		egress: make(chan Event),
+// lastPosition returns the client's state for saving. Callers must hold at
+// least the manager read lock.
+func (c *Client) lastPosition() database.LastPosition {
+	return database.LastPosition{
+		UserName: c.username,
+		Room:     c.room,
+		X:        c.state.X,
+		Y:        c.state.Y,
+		Vx:       c.state.Vx,
+		Vy:       c.state.Vy,
+		Spd:      c.state.Spd,
+		Acc:      c.state.Acc,
+		Ang:      c.state.Ang,
+	}
+}
+
+// countStat increments one of the client's session counters.
+func (c *Client) countStat(inc func(*database.SessionStats)) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	inc(&c.stats)
+}
+
+func (c *Client) sessionStats() database.SessionStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.stats
+}
+
+// enqueue queues an event for the write loop without blocking. A client whose
+// queue is full has stopped keeping up; rather than let it miss events and
+// fall out of sync it is disconnected, and can reconnect for a fresh state.
+func (c *Client) enqueue(event Event) {
+	c.egressMu.Lock()
+	defer c.egressMu.Unlock()
+	if c.egressClosed {
+		return
+	}
+
+	select {
+	case c.egress <- event:
+	default:
+		if c.overflow.CompareAndSwap(false, true) {
+			c.manager.logger.Printf("egress queue full for %s, disconnecting", c.username)
+			c.conn.Close()
+		}
+	}
+}
+
+// closeEgress closes the client's queue, ending its write loop. It is safe to
+// call more than once, and events enqueued afterwards are dropped.
+func (c *Client) closeEgress() {
+	c.egressMu.Lock()
+	defer c.egressMu.Unlock()
+	if !c.egressClosed {
+		c.egressClosed = true
+		close(c.egress)
 	}
 }
 
 func (c *Client) readMsgs() {
 	defer func() {
 		// cleanup connection
+		c.finishStrokes()
 		c.manager.removeClient(c)
 	}()
 
@@ -79,22 +232,24 @@ func (c *Client) readMsgs() {
 
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error reading Msg: %v", err)
+				c.manager.logger.Printf("error reading Msg: %v", err)
 			}
 			break
 		}
 
+		c.messagesIn.Add(1)
+
 		var request Event
 
 		err = json.Unmarshal(payload, &request)
 		if err != nil {
-			log.Printf("error unmarshalling Msg: %v", err)
+			c.manager.logger.Printf("error unmarshalling Msg: %v", err)
 			continue
 		}
 
 		err = c.manager.routeEvent(request, c)
 		if err != nil {
-			log.Printf("error routing Msg: %v", err)
+			c.manager.logger.Printf("error routing Msg: %v", err)
 			continue
 		}
 	}
@@ -112,20 +267,22 @@ func (c *Client) writeMsg() {
 			if !ok {
 				err := c.conn.WriteMessage(websocket.CloseMessage, nil)
 				if err != nil {
-					log.Printf("connection closed: %v", err)
+					c.manager.logger.Printf("connection closed: %v", err)
 				}
 				return
 			}
 			data, err := json.Marshal(msg)
 			if err != nil {
-				log.Printf("failed to marshal Msg: %v", err)
+				c.manager.logger.Printf("failed to marshal Msg: %v", err)
 				return
 			}
 
 			err = c.conn.WriteMessage(websocket.TextMessage, data)
 			if err != nil {
-				log.Printf("failed to writing Msg: %v", err)
+				c.manager.logger.Printf("failed to writing Msg: %v", err)
+				continue
 			}
+			c.messagesOut.Add(1)
 		}
 	}
 }
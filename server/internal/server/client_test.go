@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClientAccessors(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: []byte(`{"x":30,"y":40,"delta":10}`)}, c); err != nil {
+		t.Fatal(err)
+	}
+	if pos := c.Position(); pos != (Position{X: 30, Y: 40}) {
+		t.Fatalf("expected the cursor at (30, 40), got %+v", pos)
+	}
+	if state := c.State(); state.Spd != 5 {
+		t.Fatalf("expected a speed of 5, got %+v", state)
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 120, -40)
+	c.username = "ghost"
+	c.state.Vx = 3
+
+	if err := m.db.SaveLastPosition(c.lastPosition()); err != nil {
+		t.Fatal(err)
+	}
+
+	state := restoreState(m.db, "ghost", DefaultRoom)
+	if state.X != 120 || state.Y != -40 {
+		t.Fatalf("expected to restore (120, -40), got (%v, %v)", state.X, state.Y)
+	}
+	if state.Vx != 0 {
+		t.Fatal("expected motion not to be restored")
+	}
+
+	if state := restoreState(m.db, "ghost", "elsewhere"); state != NewState() {
+		t.Fatal("expected a new state in a room the user hasn't visited")
+	}
+}
+
+func TestSendKeepsOrder(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	for i := 0; i < 10; i++ {
+		event, _ := NewEvent(EventLeaderViewport, i)
+		send(event, c)
+	}
+	for i := 0; i < 10; i++ {
+		if got := string((<-c.egress).Payload); got != fmt.Sprint(i) {
+			t.Fatalf("expected event %d, got %s", i, got)
+		}
+	}
+}
+
+func TestSendDisconnectsSlowClient(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+	conn := newVirtualTransport()
+	c.conn = conn
+
+	event, _ := NewEvent(EventLeaderViewport, nil)
+	for i := 0; i <= cap(c.egress); i++ {
+		send(event, c)
+	}
+
+	select {
+	case <-conn.closed:
+	default:
+		t.Fatal("expected a client with a full queue to be disconnected")
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventCommentCreate = "comment_create"
+	EventCommentUpdate = "comment_update"
+)
+
+var errNotCommentAuthor = errors.New("only the author can edit a comment")
+
+type Comment struct {
+	ID        uuid.UUID  `json:"id"`
+	Room      string     `json:"room"`
+	Author    string     `json:"author"`
+	X         float64    `json:"x"`
+	Y         float64    `json:"y"`
+	Text      string     `json:"text"`
+	Resolved  bool       `json:"resolved"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func newComment(c database.Comment) Comment {
+	return Comment{
+		ID:        c.ID,
+		Room:      c.Room,
+		Author:    c.Author,
+		X:         c.X,
+		Y:         c.Y,
+		Text:      c.Text,
+		Resolved:  c.Resolved,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// NewCommentRequest is the body of a new comment. The author is the user
+// making the request, not something the body can claim.
+type NewCommentRequest struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Text string  `json:"text" binding:"required"`
+}
+
+// UpdateCommentRequest changes a comment. Anyone in the room may resolve or
+// reopen it, but only its author may edit the text.
+type UpdateCommentRequest struct {
+	Text     *string `json:"text"`
+	Resolved *bool   `json:"resolved"`
+}
+
+// broadcastComment pushes a created or updated comment to everyone in its
+// room who hasn't blocked the author.
+func (m *Manager) broadcastComment(eventType string, comment Comment) {
+	payload, err := json.Marshal(comment)
+	if err != nil {
+		m.logger.Printf("failed to marshal comment: %v", err)
+		return
+	}
+	m.broadcast(comment.Room, Event{Type: eventType, Payload: payload}, m.unblocked(comment.Author, nil))
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEnvInt reads an integer from the environment, falling back to def when
+// the variable is unset or malformed.
+func getEnvInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// getEnvFloat reads a float from the environment, falling back to def when
+// the variable is unset or malformed.
+func getEnvFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// getEnvBool reads a boolean from the environment, falling back to def when
+// the variable is unset or malformed.
+func getEnvBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// getEnvDuration reads a whole number of units from the environment, falling
+// back to def when the variable is unset or malformed.
+func getEnvDuration(key string, unit, def time.Duration) time.Duration {
+	return time.Duration(getEnvInt(key, int(def/unit))) * unit
+}
+
+// getEnvList reads a comma separated list from the environment, dropping empty
+// entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// envOptions configures the standalone server's manager from the environment
+// variables listed in .example.env, keeping the defaults for any that are
+// unset.
+func envOptions() []Option {
+	d := newOptions(nil)
+	return []Option{
+		WithTrustedProxies(getEnvList("TRUSTED_PROXIES")...),
+		WithAdminToken(os.Getenv("ADMIN_TOKEN")),
+		WithIngestKeys(getEnvList("INGEST_API_KEYS")...),
+		WithInterest(
+			getEnvFloat("INTEREST_CELL_SIZE", d.cellSize),
+			getEnvFloat("INTEREST_MARGIN", d.interestMargin),
+			getEnvInt("INTEREST_MAX_CURSORS", d.maxCursors),
+		),
+		WithReactions(
+			getEnvFloat("REACTION_RATE", d.reactionRate),
+			getEnvInt("REACTION_BURST", d.reactionBurst),
+			getEnvDuration("REACTION_TTL_MS", time.Millisecond, d.reactionTTL),
+		),
+		WithWebhookRetries(
+			getEnvInt("WEBHOOK_MAX_ATTEMPTS", d.webhookAttempts),
+			getEnvDuration("WEBHOOK_BACKOFF_MS", time.Millisecond, d.webhookBackoff),
+			getEnvDuration("WEBHOOK_MAX_BACKOFF_S", time.Second, d.webhookMaxBackoff),
+		),
+		WithPrivateWebhooks(getEnvBool("WEBHOOK_ALLOW_PRIVATE", d.webhookAllowPrivate)),
+		WithVirtualClientTTL(getEnvDuration("VIRTUAL_CLIENT_TTL_S", time.Second, d.virtualTTL)),
+		WithUsernameRules(
+			getEnvInt("USERNAME_MIN_LENGTH", d.usernameMin),
+			getEnvInt("USERNAME_MAX_LENGTH", d.usernameMax),
+			getEnvList("DENY_WORDS")...,
+		),
+		WithGhosts(
+			getEnvDuration("GHOST_TTL_S", time.Second, d.ghostTTL),
+			getEnvDuration("GHOST_DECAY_MS", time.Millisecond, d.ghostDecay),
+		),
+		WithTrails(
+			getEnvDuration("TRAIL_SECONDS", time.Second, d.trailWindow),
+			getEnvInt("TRAIL_MAX_RATE", d.trailRate),
+		),
+		WithPresenceInterval(getEnvDuration("PRESENCE_STREAM_INTERVAL_MS", time.Millisecond, d.presenceInterval)),
+		WithTagRadius(getEnvFloat("TAG_RADIUS", d.tagRadius)),
+		WithCheckpointInterval(getEnvDuration("CHECKPOINT_INTERVAL_S", time.Second, d.checkpointInterval)),
+	}
+}
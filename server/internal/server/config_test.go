@@ -0,0 +1,26 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnvOptions(t *testing.T) {
+	t.Setenv("INTEREST_CELL_SIZE", "64")
+	t.Setenv("GHOST_TTL_S", "3")
+	t.Setenv("DENY_WORDS", "foo, bar")
+	t.Setenv("REACTION_BURST", "lots")
+
+	o := newOptions(envOptions())
+	if o.cellSize != 64 || o.ghostTTL != 3*time.Second {
+		t.Errorf("expected the environment to set the options, got %v and %v", o.cellSize, o.ghostTTL)
+	}
+	if len(o.denyWords) != 2 || o.denyWords[1] != "bar" {
+		t.Errorf("expected the deny words to be split, got %q", o.denyWords)
+	}
+
+	d := newOptions(nil)
+	if o.reactionBurst != d.reactionBurst || o.ghostDecay != d.ghostDecay {
+		t.Errorf("expected unset and malformed variables to keep the defaults")
+	}
+}
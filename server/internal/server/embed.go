@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RegisterEvent routes events of the given type to handler, replacing the
+// built-in handler if there is one. Handlers run on the sending client's read
+// loop, so a slow handler only holds up that client.
+func (m *Manager) RegisterEvent(eventType string, handler EventHandler) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.handlers[eventType] = handler
+}
+
+// Broadcast sends the event to everyone in the room, spectators included.
+func (m *Manager) Broadcast(room string, event Event) {
+	m.broadcast(room, event, nil)
+}
+
+// Mount registers the WebSocket, SSE and REST routes on r, which may be a
+// group to serve them under a prefix. Client addresses, which bans match on,
+// follow the trusted proxies of the engine r belongs to.
+func (m *Manager) Mount(r gin.IRouter) {
+	s := &Server{db: m.db, manager: m}
+	s.mount(r)
+}
+
+// Handler returns the routes as an http.Handler for mounting on routers other
+// than gin.
+func (m *Manager) Handler() http.Handler {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	m.trustProxies(r)
+	m.Mount(r)
+	return r
+}
+
+// Close stops the manager's background work, such as expiring ghosts and
+// saving checkpoints, and waits for webhook deliveries in flight until ctx is
+// done. Deliveries waiting to retry are dead-lettered instead. Connections are
+// left to the HTTP server's shutdown.
+func (m *Manager) Close(ctx context.Context) error {
+	m.closeOnce.Do(func() { close(m.done) })
+
+	delivered := make(chan struct{})
+	go func() {
+		m.webhooks.wait()
+		close(delivered)
+	}()
+
+	select {
+	case <-delivered:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) ID() uuid.UUID {
+	return c.id
+}
+
+func (c *Client) Username() string {
+	return c.username
+}
+
+func (c *Client) Room() string {
+	return c.room
+}
+
+// Position returns where the client's cursor is on the canvas.
+func (c *Client) Position() Position {
+	c.manager.RLock()
+	defer c.manager.RUnlock()
+	return Position{X: c.state.X, Y: c.state.Y}
+}
+
+// State returns a snapshot of the client's cursor: its position and how it
+// is moving.
+func (c *Client) State() State {
+	c.manager.RLock()
+	defer c.manager.RUnlock()
+	return c.state
+}
+
+// Spectator reports whether the client is a read-only connection without a
+// cursor.
+func (c *Client) Spectator() bool {
+	return c.spectator
+}
+
+// Send queues the event for the client without blocking the caller.
+func (c *Client) Send(event Event) {
+	send(event, c)
+}
@@ -2,8 +2,11 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"math"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
 )
 
 type Event struct {
@@ -13,10 +16,35 @@ type Event struct {
 
 type EventHandler func(event Event, c *Client) error
 
+func NewEvent(eventType string, v interface{}) (Event, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, Payload: payload}, nil
+}
+
+// send queues the event for each client. It never blocks, so it can be used
+// while the manager lock is held, and events reach a client in the order they
+// were sent.
+func send(event Event, clients ...*Client) {
+	for _, client := range clients {
+		client.enqueue(event)
+	}
+}
+
 const (
 	EventUpdatePosition = "update_position"
+	EventUpdateViewport = "update_viewport"
+	EventPointerDown    = "pointer_down"
+	EventPointerUp      = "pointer_up"
+	EventClick          = "click"
+	EventDrag           = "drag"
+	EventCursorEffect   = "cursor_effect"
 )
 
+var errInvalidPosition = errors.New("position must be finite and within the canvas")
+
 type UpdatePositionEvent struct {
 	X     float64 `json:"x"`
 	Y     float64 `json:"y"`
@@ -31,15 +59,28 @@ func UpdatePosition(event Event, c *Client) error {
 		return err
 	}
 
-	log.Printf("Update: %s ->    x %d   y %d", c.username, int(update.X), int(update.Y))
+	// huge coordinates would overflow the motion into Inf or NaN, which can't
+	// be encoded for anyone in the room
+	if !validPosition(update.X, update.Y) {
+		return errInvalidPosition
+	}
+
+	c.manager.logger.Printf("Update: %s ->    x %d   y %d", c.username, int(update.X), int(update.Y))
 
+	// virtual clients can be moved by concurrent ingestion requests, so the
+	// previous state is read under the same lock it is written with
+	c.manager.Lock()
 	prevPos := Position{X: c.state.X, Y: c.state.Y}
 	curPos := Position{X: update.X, Y: update.Y}
 
-	vx, vy := velocity(prevPos, curPos, float64(update.Delta))
+	// the delta comes from the client, and a zero or negative one would
+	// make the motion infinite
+	delta := float64(max(update.Delta, 1))
+
+	vx, vy := velocity(prevPos, curPos, delta)
 	ang := angle(prevPos, curPos)
 	spd := speed(vx, vy)
-	acc := acceleration(c.state.Spd, spd, float64(update.Delta))
+	acc := acceleration(c.state.Spd, spd, delta)
 
 	c.state.X = curPos.X
 	c.state.Y = curPos.Y
@@ -48,13 +89,63 @@ func UpdatePosition(event Event, c *Client) error {
 	c.state.Ang = ang
 	c.state.Spd = spd
 	c.state.Acc = acc
+	c.manager.gridFor(c.room).move(c, curPos.X, curPos.Y)
+	c.manager.recordTrail(c, TrailPoint{X: curPos.X, Y: curPos.Y, T: time.Now().UnixMilli()})
+	crossings := c.manager.updateZones(c, time.Now())
+	game := c.manager.applyBehaviour(c.room, func(b RoomBehaviour) bool {
+		return b.Move(c, c.manager.roomPeers(c))
+	})
+	c.manager.Unlock()
 
-	for client := range c.manager.Clients {
-		broadcastState(client)
-	}
+	c.manager.publishCrossings(crossings)
+	c.manager.publishBehaviour(game)
+
+	dx, dy := displacement(prevPos, curPos)
+	c.countStat(func(s *database.SessionStats) {
+		// the first update jumps from wherever the cursor started, so it
+		// doesn't count as movement
+		if s.PositionUpdates > 0 {
+			if moved := math.Hypot(dx, dy); !math.IsInf(moved, 0) && !math.IsNaN(moved) {
+				s.Distance += moved
+			}
+			if !math.IsInf(spd, 0) && !math.IsNaN(spd) {
+				s.TopSpeed = math.Max(s.TopSpeed, spd)
+			}
+		}
+		s.PositionUpdates++
+	})
+
+	c.manager.broadcastRoomState(c.room)
 	return nil
 }
 
+// UpdateViewport records the area of the canvas the client is looking at so
+// broadcasts can be limited to nearby cursors, and forwards it to anyone
+// following the client. An empty viewport clears it and the client receives
+// every cursor again.
+func UpdateViewport(event Event, c *Client) error {
+	var viewport Viewport
+	err := json.Unmarshal(event.Payload, &viewport)
+	if err != nil {
+		return err
+	}
+	if !viewport.valid() {
+		return errInvalidViewport
+	}
+	viewport = viewport.clamp()
+
+	c.manager.Lock()
+	if viewport.W <= 0 || viewport.H <= 0 {
+		c.viewport = nil
+	} else {
+		c.viewport = &viewport
+	}
+	c.manager.notifyFollowers(c)
+	c.manager.Unlock()
+
+	return broadcastState(c)
+}
+
 type Position struct {
 	X, Y float64
 }
@@ -88,24 +179,90 @@ func acceleration(v1, v2 float64, deltaTime float64) float64 {
 func broadcastState(c *Client) error {
 	payloadJson := make(map[string]interface{})
 
-	for client := range c.manager.Clients {
-		payloadJson[client.id.String()] = map[string]interface{}{
-			"username": client.username,
-			"color":    client.color,
-			"mood":     client.mood,
-			"state":    client.state,
+	c.manager.RLock()
+	for _, client := range c.manager.visibleClients(c) {
+		cursor := map[string]interface{}{
+			"username":     client.username,
+			"color":        client.color,
+			"mood":         client.mood,
+			"state":        client.state,
+			"disconnected": !client.disconnectedAt.IsZero(),
+		}
+		if !client.disconnectedAt.IsZero() {
+			cursor["expires_at"] = client.disconnectedAt.Add(c.manager.ghostTTL)
 		}
+		payloadJson[client.id.String()] = cursor
 	}
+	c.manager.RUnlock()
 
 	json, err := json.Marshal(payloadJson)
 	if err != nil {
 		return err
 	}
 
-	c.egress <- Event{
+	c.enqueue(Event{
 		Type:    "broadcast",
 		Payload: json,
+	})
+
+	return nil
+}
+
+type Modifiers struct {
+	Alt   bool `json:"alt"`
+	Ctrl  bool `json:"ctrl"`
+	Meta  bool `json:"meta"`
+	Shift bool `json:"shift"`
+}
+
+type PointerEvent struct {
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	Button    int       `json:"button"`
+	Modifiers Modifiers `json:"modifiers"`
+}
+
+// PointerEffect handles presses, releases, clicks and drags. They are relayed
+// to the rest of the room as cursor effects so other users can render ripples
+// and drag trails at the given location.
+func PointerEffect(event Event, c *Client) error {
+	var pointer PointerEvent
+	err := json.Unmarshal(event.Payload, &pointer)
+	if err != nil {
+		return err
+	}
+
+	c.countStat(func(s *database.SessionStats) {
+		switch event.Type {
+		case EventPointerDown:
+			s.PointerDowns++
+		case EventPointerUp:
+			s.PointerUps++
+		case EventClick:
+			s.Clicks++
+		case EventDrag:
+			s.Drags++
+		}
+	})
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":        c.id.String(),
+		"username":  c.username,
+		"color":     c.color,
+		"kind":      event.Type,
+		"x":         pointer.X,
+		"y":         pointer.Y,
+		"button":    pointer.Button,
+		"modifiers": pointer.Modifiers,
+	})
+	if err != nil {
+		return err
 	}
 
+	c.manager.broadcast(c.room, Event{Type: EventCursorEffect, Payload: payload}, c.manager.unblocked(c.username, func(client *Client) bool {
+		c.manager.RLock()
+		defer c.manager.RUnlock()
+		return c.manager.interested(client, pointer.X, pointer.Y)
+	}))
 	return nil
 }
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestPointerEffect(t *testing.T) {
+	m := newTestManager(t)
+	sender := addTestClient(m, 0, 0)
+	receiver := addTestClient(m, 10, 10)
+	other := addTestClient(m, 10, 10)
+	other.room = "elsewhere"
+
+	err := PointerEffect(Event{
+		Type:    EventClick,
+		Payload: json.RawMessage(`{"x":5,"y":5,"button":0,"modifiers":{"shift":true}}`),
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-receiver.egress:
+		if event.Type != EventCursorEffect {
+			t.Fatalf("expected %s, got %s", EventCursorEffect, event.Type)
+		}
+	default:
+		t.Fatal("expected receiver to get the cursor effect")
+	}
+
+	if len(other.egress) != 0 {
+		t.Fatal("expected client in another room not to get the cursor effect")
+	}
+
+	if stats := sender.sessionStats(); stats.Clicks != 1 {
+		t.Fatalf("expected 1 click to be counted, got %d", stats.Clicks)
+	}
+}
+
+func TestUpdatePositionStats(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	for _, payload := range []string{
+		`{"x":300,"y":400,"delta":100}`,
+		`{"x":300,"y":410,"delta":100}`,
+		`{"x":330,"y":450,"delta":100}`,
+	} {
+		if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: json.RawMessage(payload)}, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := c.sessionStats()
+	if stats.PositionUpdates != 3 {
+		t.Fatalf("expected 3 position updates, got %d", stats.PositionUpdates)
+	}
+	// the jump from the origin is ignored
+	if stats.Distance != 60 {
+		t.Fatalf("expected a distance of 60, got %v", stats.Distance)
+	}
+	if stats.TopSpeed != 0.5 {
+		t.Fatalf("expected a top speed of 0.5, got %v", stats.TopSpeed)
+	}
+}
+
+func TestUpdatePositionOutOfRange(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: json.RawMessage(`{"x":1e308,"y":0,"delta":1}`)}, c); err != errInvalidPosition {
+		t.Fatalf("expected an overflowing position to be rejected, got %v", err)
+	}
+	if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: json.RawMessage(`{"x":-1e308,"y":0,"delta":1}`)}, c); err != errInvalidPosition {
+		t.Fatalf("expected an overflowing position to be rejected, got %v", err)
+	}
+	if c.state.X != 0 || c.state.Vx != 0 {
+		t.Fatalf("expected the state to be unchanged, got %+v", c.state)
+	}
+	if _, err := json.Marshal(c.state); err != nil {
+		t.Fatalf("expected the state to stay encodable: %v", err)
+	}
+}
+
+func TestUpdatePositionBadDelta(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	for _, payload := range []string{
+		`{"x":0,"y":0,"delta":100}`,
+		`{"x":10,"y":0,"delta":0}`,
+		`{"x":20,"y":0,"delta":-5}`,
+	} {
+		if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: json.RawMessage(payload)}, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := c.sessionStats()
+	if math.IsInf(stats.TopSpeed, 0) || math.IsNaN(stats.TopSpeed) || stats.TopSpeed != 10 {
+		t.Fatalf("expected a finite top speed of 10, got %v", stats.TopSpeed)
+	}
+	if _, err := json.Marshal(c.state); err != nil {
+		t.Fatalf("expected the state to stay encodable: %v", err)
+	}
+}
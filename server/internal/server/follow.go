@@ -0,0 +1,266 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventFollow         = "follow"
+	EventUnfollow       = "unfollow"
+	EventSpotlight      = "spotlight"
+	EventFollowStart    = "follow_start"
+	EventFollowEnd      = "follow_end"
+	EventLeaderViewport = "leader_viewport"
+)
+
+var (
+	errLeaderNotFound = errors.New("leader not found")
+	errFollowSelf     = errors.New("cannot follow yourself")
+)
+
+type FollowEvent struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type SpotlightEvent struct {
+	Active bool `json:"active"`
+}
+
+type Leader struct {
+	ID       string    `json:"id"`
+	Username string    `json:"username"`
+	Viewport *Viewport `json:"viewport,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+type Spotlight struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Active   bool   `json:"active"`
+}
+
+// clientByID finds a connected client. Callers must hold the manager lock.
+func (m *Manager) clientByID(id uuid.UUID) *Client {
+	for client := range m.Clients {
+		if client.id == id {
+			return client
+		}
+	}
+	return nil
+}
+
+// follow makes follower receive leader's viewport updates, replacing any
+// leader it was already following. Callers must hold the manager lock.
+func (m *Manager) follow(follower, leader *Client) {
+	m.unfollow(follower)
+
+	followers, ok := m.followers[leader]
+	if !ok {
+		followers = make(ClientList)
+		m.followers[leader] = followers
+	}
+	followers[follower] = true
+	m.leaders[follower] = leader
+}
+
+// unfollow stops follower from following its leader, if it has one. Callers
+// must hold the manager lock.
+func (m *Manager) unfollow(follower *Client) *Client {
+	leader, ok := m.leaders[follower]
+	if !ok {
+		return nil
+	}
+
+	delete(m.leaders, follower)
+	delete(m.followers[leader], follower)
+	if len(m.followers[leader]) == 0 {
+		delete(m.followers, leader)
+	}
+	return leader
+}
+
+// dropFollows clears every follow relationship involving the client when it
+// disconnects, telling its followers, and the room if it held the spotlight,
+// that it has gone. Callers must hold the manager lock.
+func (m *Manager) dropFollows(c *Client) {
+	m.unfollow(c)
+
+	leader := Leader{ID: c.id.String(), Username: c.username, Reason: "disconnected"}
+	var followers []*Client
+	for follower := range m.followers[c] {
+		delete(m.leaders, follower)
+		followers = append(followers, follower)
+	}
+	delete(m.followers, c)
+
+	if len(followers) > 0 {
+		if event, err := NewEvent(EventFollowEnd, leader); err == nil {
+			send(event, followers...)
+		}
+	}
+
+	if m.spotlights[c.room] == c {
+		delete(m.spotlights, c.room)
+		m.announceSpotlight(c, false)
+	}
+}
+
+// announceSpotlight tells everyone else in the room that the client has
+// started or stopped presenting. Callers must hold the manager lock.
+func (m *Manager) announceSpotlight(c *Client, active bool) {
+	event, err := NewEvent(EventSpotlight, Spotlight{
+		ID:       c.id.String(),
+		Username: c.username,
+		Active:   active,
+	})
+	if err != nil {
+		m.logger.Printf("failed to marshal spotlight: %v", err)
+		return
+	}
+
+	// spectators watch the presenter too
+	var room []*Client
+	for _, list := range []ClientList{m.Clients, m.spectators} {
+		for client := range list {
+			if client.room == c.room && client != c {
+				room = append(room, client)
+			}
+		}
+	}
+	send(event, room...)
+}
+
+// syncSpotlight tells a newly connected client who is presenting in its room.
+func (m *Manager) syncSpotlight(c *Client) error {
+	m.RLock()
+	presenter, ok := m.spotlights[c.room]
+	m.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	event, err := NewEvent(EventSpotlight, Spotlight{
+		ID:       presenter.id.String(),
+		Username: presenter.username,
+		Active:   true,
+	})
+	if err != nil {
+		return err
+	}
+	c.enqueue(event)
+	return nil
+}
+
+// notifyFollowers forwards the client's viewport to everyone following it.
+// Callers must hold at least the manager read lock.
+func (m *Manager) notifyFollowers(c *Client) {
+	followers := m.followers[c]
+	if len(followers) == 0 {
+		return
+	}
+
+	event, err := NewEvent(EventLeaderViewport, Leader{
+		ID:       c.id.String(),
+		Username: c.username,
+		Viewport: c.viewport,
+	})
+	if err != nil {
+		m.logger.Printf("failed to marshal leader viewport: %v", err)
+		return
+	}
+
+	var clients []*Client
+	for follower := range followers {
+		clients = append(clients, follower)
+	}
+	send(event, clients...)
+}
+
+// Follow starts streaming another client's viewport updates to this client.
+func Follow(event Event, c *Client) error {
+	var follow FollowEvent
+	err := json.Unmarshal(event.Payload, &follow)
+	if err != nil {
+		return err
+	}
+
+	m := c.manager
+	m.Lock()
+	defer m.Unlock()
+
+	leader := m.clientByID(follow.ID)
+	if leader == nil || leader.room != c.room {
+		return errLeaderNotFound
+	}
+	if leader == c {
+		return errFollowSelf
+	}
+
+	m.follow(c, leader)
+
+	start, err := NewEvent(EventFollowStart, Leader{
+		ID:       leader.id.String(),
+		Username: leader.username,
+		Viewport: leader.viewport,
+	})
+	if err != nil {
+		return err
+	}
+	send(start, c)
+	return nil
+}
+
+func Unfollow(event Event, c *Client) error {
+	m := c.manager
+	m.Lock()
+	defer m.Unlock()
+
+	leader := m.unfollow(c)
+	if leader == nil {
+		return nil
+	}
+
+	end, err := NewEvent(EventFollowEnd, Leader{
+		ID:       leader.id.String(),
+		Username: leader.username,
+		Reason:   "unfollowed",
+	})
+	if err != nil {
+		return err
+	}
+	send(end, c)
+	return nil
+}
+
+// SpotlightSelf lets a presenter ask everyone in the room to follow them. Only
+// one client holds the spotlight per room; a new presenter replaces the last.
+func SpotlightSelf(event Event, c *Client) error {
+	var spotlight SpotlightEvent
+	err := json.Unmarshal(event.Payload, &spotlight)
+	if err != nil {
+		return err
+	}
+
+	m := c.manager
+	m.Lock()
+	defer m.Unlock()
+
+	current, ok := m.spotlights[c.room]
+	if !spotlight.Active {
+		if ok && current == c {
+			delete(m.spotlights, c.room)
+			m.announceSpotlight(c, false)
+		}
+		return nil
+	}
+
+	if ok && current != c {
+		m.announceSpotlight(current, false)
+	}
+	m.spotlights[c.room] = c
+	m.announceSpotlight(c, true)
+	return nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, c *Client, eventType string) Event {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-c.egress:
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+		}
+	}
+}
+
+func TestFollowLeaderDisconnect(t *testing.T) {
+	m := newTestManager(t)
+	leader := addTestClient(m, 0, 0)
+	follower := addTestClient(m, 0, 0)
+
+	payload := json.RawMessage(fmt.Sprintf(`{"id":%q}`, leader.id))
+	if err := Follow(Event{Type: EventFollow, Payload: payload}, follower); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, follower, EventFollowStart)
+
+	viewport := json.RawMessage(`{"x":0,"y":0,"w":800,"h":600}`)
+	if err := UpdateViewport(Event{Type: EventUpdateViewport, Payload: viewport}, leader); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, follower, EventLeaderViewport)
+
+	m.Lock()
+	m.dropFollows(leader)
+	m.Unlock()
+
+	var end Leader
+	if err := json.Unmarshal(receive(t, follower, EventFollowEnd).Payload, &end); err != nil {
+		t.Fatal(err)
+	}
+	if end.Reason != "disconnected" {
+		t.Fatalf("expected disconnected reason, got %q", end.Reason)
+	}
+	if _, ok := m.leaders[follower]; ok {
+		t.Fatal("expected follower to be released")
+	}
+}
+
+func TestSpotlight(t *testing.T) {
+	m := newTestManager(t)
+	presenter := addTestClient(m, 0, 0)
+	audience := addTestClient(m, 0, 0)
+	spectator := NewSpectator(DefaultRoom, nil, m)
+	m.addSpectator(spectator)
+
+	if err := SpotlightSelf(Event{Type: EventSpotlight, Payload: json.RawMessage(`{"active":true}`)}, presenter); err != nil {
+		t.Fatal(err)
+	}
+
+	var spotlight Spotlight
+	if err := json.Unmarshal(receive(t, audience, EventSpotlight).Payload, &spotlight); err != nil {
+		t.Fatal(err)
+	}
+	if !spotlight.Active || spotlight.ID != presenter.id.String() {
+		t.Fatalf("unexpected spotlight: %+v", spotlight)
+	}
+	receive(t, spectator, EventSpotlight)
+
+	m.Lock()
+	m.dropFollows(presenter)
+	m.Unlock()
+
+	if err := json.Unmarshal(receive(t, audience, EventSpotlight).Payload, &spotlight); err != nil {
+		t.Fatal(err)
+	}
+	if spotlight.Active {
+		t.Fatal("expected spotlight to end when the presenter disconnects")
+	}
+}
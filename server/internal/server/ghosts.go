@@ -0,0 +1,117 @@
+package server
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	ghostTick = 100 * time.Millisecond
+
+	// ghostRestSpeed is the speed, in px/ms, below which a ghost stops
+	// drifting
+	ghostRestSpeed = 0.001
+)
+
+// linger keeps a disconnected client in its room as a ghost until the ghost
+// TTL passes. Ghosts stay in the spatial index and are broadcast flagged as
+// disconnected. Callers must hold the manager lock.
+func (m *Manager) linger(c *Client, now time.Time) {
+	if m.ghostTTL <= 0 {
+		m.gridFor(c.room).remove(c)
+		return
+	}
+
+	c.disconnectedAt = now
+	m.ghosts[c] = true
+}
+
+// revive hands a reconnecting user the identity, position and trail of their
+// ghost in the room, if one is still lingering, and removes the ghost. Others
+// see the same cursor come back rather than one vanish and another appear;
+// the new connection still records its own session. Callers must hold the
+// manager lock.
+func (m *Manager) revive(c *Client) {
+	for ghost := range m.ghosts {
+		if ghost.username != c.username || ghost.room != c.room {
+			continue
+		}
+
+		c.id = ghost.id
+		c.state.X = ghost.state.X
+		c.state.Y = ghost.state.Y
+		if ghost.trail != nil {
+			c.trail = ghost.trail
+		}
+		m.banish(ghost)
+		return
+	}
+}
+
+// banish removes a ghost for good. Callers must hold the manager lock.
+func (m *Manager) banish(ghost *Client) {
+	delete(m.ghosts, ghost)
+	m.gridFor(ghost.room).remove(ghost)
+}
+
+// driftGhosts moves each ghost along its last velocity, which decays
+// exponentially, and removes ghosts that have expired. It returns the rooms
+// whose ghosts changed. Callers must hold the manager lock.
+func (m *Manager) driftGhosts(now time.Time, dt time.Duration) map[string]bool {
+	changed := make(map[string]bool)
+	ms := float64(dt.Milliseconds())
+	decay := 0.0
+	if m.ghostDecay > 0 {
+		decay = math.Exp(-ms / float64(m.ghostDecay.Milliseconds()))
+	}
+
+	for ghost := range m.ghosts {
+		if now.Sub(ghost.disconnectedAt) >= m.ghostTTL {
+			m.banish(ghost)
+			changed[ghost.room] = true
+			continue
+		}
+
+		if ghost.state.Spd < ghostRestSpeed {
+			continue
+		}
+
+		ghost.state.X += ghost.state.Vx * ms
+		ghost.state.Y += ghost.state.Vy * ms
+		ghost.state.Vx *= decay
+		ghost.state.Vy *= decay
+		ghost.state.Spd = speed(ghost.state.Vx, ghost.state.Vy)
+		ghost.state.Acc = 0
+		if ghost.state.Spd < ghostRestSpeed {
+			ghost.state.Vx, ghost.state.Vy, ghost.state.Spd = 0, 0, 0
+		}
+		m.gridFor(ghost.room).move(ghost, ghost.state.X, ghost.state.Y)
+		changed[ghost.room] = true
+	}
+	return changed
+}
+
+// runGhosts animates lingering ghosts and broadcasts the rooms they are in.
+func (m *Manager) runGhosts() {
+	ticker := time.NewTicker(ghostTick)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-m.done:
+			return
+		}
+
+		m.Lock()
+		rooms := m.driftGhosts(now, now.Sub(last))
+		m.Unlock()
+		last = now
+
+		for room := range rooms {
+			m.broadcastRoomState(room)
+		}
+	}
+}
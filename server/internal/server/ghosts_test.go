@@ -0,0 +1,90 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGhostDriftAndExpire(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+	c.state.Vx, c.state.Spd = 1, 1
+
+	now := time.Now()
+	delete(m.Clients, c)
+	m.linger(c, now)
+
+	receiver := addTestClient(m, 0, 0)
+	if visible := m.visibleClients(receiver); len(visible) != 2 {
+		t.Fatalf("expected the ghost to stay visible, got %d cursors", len(visible))
+	}
+
+	m.driftGhosts(now.Add(100*time.Millisecond), 100*time.Millisecond)
+	if c.state.X != 100 {
+		t.Fatalf("expected ghost to drift 100px, got %v", c.state.X)
+	}
+	if c.state.Vx >= 1 {
+		t.Fatal("expected ghost velocity to decay")
+	}
+
+	m.driftGhosts(now.Add(m.ghostTTL), 100*time.Millisecond)
+	if _, ok := m.ghosts[c]; ok {
+		t.Fatal("expected ghost to be removed after its TTL")
+	}
+	if visible := m.visibleClients(receiver); len(visible) != 1 {
+		t.Fatalf("expected the ghost to be gone, got %d cursors", len(visible))
+	}
+}
+
+func TestGhostRevive(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+	c.username = "ghost"
+	c.state.X, c.state.Y = 42, 24
+
+	delete(m.Clients, c)
+	m.linger(c, time.Now())
+
+	returning := &Client{id: uuid.New(), session: uuid.New(), username: "ghost", room: DefaultRoom}
+	session := returning.session
+	m.revive(returning)
+
+	if returning.id != c.id {
+		t.Fatalf("expected the returning client to keep the ghost's id %s, got %s", c.id, returning.id)
+	}
+	if returning.session != session {
+		t.Fatal("expected the returning client to keep its own session")
+	}
+
+	if returning.state.X != 42 || returning.state.Y != 24 {
+		t.Fatalf("expected to continue from the ghost's position, got (%v, %v)", returning.state.X, returning.state.Y)
+	}
+	if len(m.ghosts) != 0 {
+		t.Fatal("expected the ghost to be replaced by the returning client")
+	}
+}
+
+func TestRemoveClientEndsWriteLoop(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+	c.conn = newSSETransport()
+
+	done := make(chan struct{})
+	go func() {
+		c.writeMsg()
+		close(done)
+	}()
+
+	m.removeClient(c)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the write loop to end once the client was removed")
+	}
+
+	// events sent to the ghost afterwards are dropped
+	c.enqueue(Event{Type: EventUpdatePosition})
+	m.removeClient(c)
+}
@@ -4,10 +4,13 @@ import (
 	"errors"
 	"log"
 	"net/http"
-	"server/internal/database"
 	"sync"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"golang.org/x/exp/rand"
 )
@@ -20,44 +23,261 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// DefaultRoom is the room clients join when they do not ask for one.
+const DefaultRoom = "lobby"
+
 type Manager struct {
-	Clients ClientList
-	db      database.Service
+	Clients    ClientList
+	spectators ClientList
+	db         database.Service
+	logger     *log.Logger
+	auth       Authenticator
 	sync.RWMutex
 
+	// proxies whose forwarded client addresses are believed
+	trustedProxies []string
+
+	// credentials for the admin and ingestion APIs, which are disabled
+	// without them
+	adminToken string
+	ingestKeys []string
+
 	handlers map[string]EventHandler
+
+	// interest management
+	grids          map[string]*spatialGrid
+	cellSize       float64
+	interestMargin float64
+	maxCursors     int
+
+	// shared objects by room
+	objects   map[string]map[uuid.UUID]*SharedObject
+	objectSeq int64
+
+	// reactions
+	reactionRate  float64
+	reactionBurst int
+	reactionTTL   time.Duration
+
+	// follow and spotlight
+	followers  map[*Client]ClientList
+	leaders    map[*Client]*Client
+	spotlights map[string]*Client
+
+	// zones and open polls by room
+	zones map[string]map[uuid.UUID]*Zone
+	polls map[string]map[uuid.UUID]*Poll
+
+	webhooks *webhookDispatcher
+	bans     *banList
+	profile  *profileRules
+	users    *userFeed
+
+	// SSE fallback connections by session token
+	fallbacks map[string]*sseTransport
+
+	// clients driven over the ingestion API, by room and username
+	virtuals   map[string]*Client
+	virtualTTL time.Duration
+
+	// disconnected clients lingering as ghosts
+	ghosts     ClientList
+	ghostTTL   time.Duration
+	ghostDecay time.Duration
+
+	// recent positions kept for late joiners, by room, and the most points
+	// kept per second of window
+	trailWindows map[string]time.Duration
+	defaultTrail time.Duration
+	trailRate    int
+
+	// how often the presence stream checks for changes
+	presenceInterval time.Duration
+
+	// room behaviours such as games
+	behaviourFactories map[string]BehaviourFactory
+	behaviours         map[string]RoomBehaviour
+
+	// closed to stop the background loops
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewManager(db *database.Service) *Manager {
+func NewManager(db *database.Service, opts ...Option) *Manager {
+	o := newOptions(opts)
+	done := make(chan struct{})
+
 	m := &Manager{
-		Clients:  make(ClientList),
-		db:       *db,
-		handlers: make(map[string]EventHandler),
+		logger:         o.logger,
+		auth:           o.auth,
+		trustedProxies: o.trustedProxies,
+		adminToken:     o.adminToken,
+		ingestKeys:     o.ingestKeys,
+		Clients:        make(ClientList),
+		spectators:     make(ClientList),
+		db:             *db,
+		handlers:       make(map[string]EventHandler),
+		grids:          make(map[string]*spatialGrid),
+		cellSize:       o.cellSize,
+		interestMargin: o.interestMargin,
+		maxCursors:     o.maxCursors,
+		objects:        make(map[string]map[uuid.UUID]*SharedObject),
+		reactionRate:   o.reactionRate,
+		reactionBurst:  o.reactionBurst,
+		reactionTTL:    o.reactionTTL,
+		followers:      make(map[*Client]ClientList),
+		leaders:        make(map[*Client]*Client),
+		spotlights:     make(map[string]*Client),
+		zones:          make(map[string]map[uuid.UUID]*Zone),
+		polls:          make(map[string]map[uuid.UUID]*Poll),
+		webhooks:       newWebhookDispatcher(*db, o.logger, done, o.webhookAllowPrivate, o.webhookAttempts, o.webhookBackoff, o.webhookMaxBackoff),
+		bans:           newBanList(*db, o.logger),
+		users:          newUserFeed(*db, o.logger),
+		fallbacks:      make(map[string]*sseTransport),
+		virtuals:       make(map[string]*Client),
+		virtualTTL:     o.virtualTTL,
+		profile:        newProfileRules(o.usernameMin, o.usernameMax, o.denyWords),
+		ghosts:         make(ClientList),
+		ghostTTL:       o.ghostTTL,
+		ghostDecay:     o.ghostDecay,
+		trailWindows:   make(map[string]time.Duration),
+		defaultTrail:   o.trailWindow,
+		trailRate:      o.trailRate,
+
+		presenceInterval: o.presenceInterval,
+
+		behaviourFactories: make(map[string]BehaviourFactory),
+		behaviours:         make(map[string]RoomBehaviour),
+
+		done: done,
 	}
 
 	m.setupHandlers()
 
+	m.RegisterBehaviour(BehaviourTag, func() RoomBehaviour {
+		return newTagGame(o.tagRadius)
+	})
+
+	m.users.load()
+
+	go m.runPolls()
+	go m.runGhosts()
+	go m.runVirtuals()
+	go m.runCheckpoints(o.checkpointInterval)
+
 	return m
 
 }
 
 func (m *Manager) setupHandlers() {
-	m.handlers["update_position"] = UpdatePosition
+	m.handlers[EventUpdatePosition] = UpdatePosition
+	m.handlers[EventUpdateViewport] = UpdateViewport
+	m.handlers[EventPointerDown] = PointerEffect
+	m.handlers[EventPointerUp] = PointerEffect
+	m.handlers[EventClick] = PointerEffect
+	m.handlers[EventDrag] = PointerEffect
+	m.handlers[EventObjectCreate] = CreateObject
+	m.handlers[EventObjectGrab] = GrabObject
+	m.handlers[EventObjectMove] = MoveObject
+	m.handlers[EventObjectRelease] = ReleaseObject
+	m.handlers[EventStrokeBegin] = BeginStroke
+	m.handlers[EventStrokePoint] = AddStrokePoints
+	m.handlers[EventStrokeEnd] = EndStroke
+	m.handlers[EventStrokeErase] = EraseStroke
+	m.handlers[EventReaction] = React
+	m.handlers[EventFollow] = Follow
+	m.handlers[EventUnfollow] = Unfollow
+	m.handlers[EventSpotlight] = SpotlightSelf
 }
 
 func (m *Manager) initiateWSConnection(c *gin.Context) {
-	username := c.Query("username")
+	username, ok := m.admit(c)
+	if !ok {
+		return
+	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("%s, error while Upgrading websocket connection\n", err.Error())
+		m.logger.Printf("%s, error while Upgrading websocket connection\n", err.Error())
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	log.Printf("New connection from %s\n", username)
+	m.connect(c, conn, username)
+}
+
+// trustProxies only lets r take client addresses from X-Forwarded-For when the
+// request comes through a trusted proxy, so a ban can't be dodged by sending
+// the header.
+func (m *Manager) trustProxies(r *gin.Engine) {
+	if err := r.SetTrustedProxies(m.trustedProxies); err != nil {
+		m.logger.Printf("error setting trusted proxies, trusting none: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+}
+
+// admit identifies the user behind a connection request, with the manager's
+// authenticator if it has one, and checks them against the bans. Usernames
+// clients choose for themselves must also follow the username rules; the
+// authenticator's are the embedding service's to vet. It writes an error
+// response and returns false if the request is refused.
+func (m *Manager) admit(c *gin.Context) (string, bool) {
+	username := c.Query("username")
+	if m.auth != nil {
+		var err error
+		username, err = m.auth(c.Request)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return "", false
+		}
+	}
+
+	ban, ok, err := m.bans.match(username, c.ClientIP())
+	if err != nil {
+		m.logger.Printf("error loading bans: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errBansUnavailable.Error()})
+		return "", false
+	}
+	if ok {
+		m.logger.Printf("Refused banned connection from %s (%s)\n", username, c.ClientIP())
+		c.JSON(http.StatusForbidden, gin.H{"error": banReason(ban)})
+		return "", false
+	}
+
+	if m.auth == nil && c.Query("mode") != ModeSpectate {
+		if err := m.profile.validUsername(username); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return "", false
+		}
+	}
+	return username, true
+}
+
+// connect starts a client or spectator over an admitted connection, whichever
+// transport it uses.
+func (m *Manager) connect(c *gin.Context, conn transport, username string) *Client {
+	room := c.DefaultQuery("room", DefaultRoom)
+
+	if c.Query("mode") == ModeSpectate {
+		m.logger.Printf("New spectator in %s\n", room)
 
-	client := NewClient(username, conn, m)
+		client := NewSpectator(room, conn, m)
+		client.ip, client.userAgent = c.ClientIP(), c.Request.UserAgent()
+		m.addSpectator(client)
+
+		go client.readMsgs()
+		go client.writeMsg()
+
+		m.syncClient(client)
+		broadcastState(client)
+		return client
+	}
+
+	m.logger.Printf("New connection from %s in %s\n", username, room)
+
+	client := NewClient(username, room, conn, m)
+	client.ip, client.userAgent = c.ClientIP(), c.Request.UserAgent()
+	client.token = sessionToken(conn)
 
 	m.addClient(client)
 
@@ -65,6 +285,28 @@ func (m *Manager) initiateWSConnection(c *gin.Context) {
 	go client.readMsgs()
 	go client.writeMsg()
 
+	// SSE clients get their session as the first event of the stream
+	if _, ok := conn.(*sseTransport); !ok {
+		if event, err := NewEvent(EventSession, Session{ID: client.id.String(), Token: client.token}); err == nil {
+			client.enqueue(event)
+		}
+	}
+
+	m.syncClient(client)
+	return client
+}
+
+// syncClient sends a newly connected client the current state of its room.
+func (m *Manager) syncClient(client *Client) {
+	if err := m.syncObjects(client); err != nil {
+		m.logger.Printf("error syncing objects: %v", err)
+	}
+	if err := m.syncSpotlight(client); err != nil {
+		m.logger.Printf("error syncing spotlight: %v", err)
+	}
+	if err := m.syncTrails(client); err != nil {
+		m.logger.Printf("error syncing trails: %v", err)
+	}
 }
 
 func (m *Manager) addClient(client *Client) {
@@ -77,27 +319,160 @@ func (m *Manager) addClient(client *Client) {
 	})
 
 	m.db.CreateSession(database.Session{
-		ID:       client.id,
+		ID:       client.session,
 		UserName: client.username,
+		Room:     client.room,
 	})
 
+	m.revive(client)
 	m.Clients[client] = true
+	m.gridFor(client.room).move(client, client.state.X, client.state.Y)
+	m.roomPolls(client.room)
+	m.users.connect(client)
+	m.webhooks.dispatch(EventUserJoin, WebhookUser{
+		ID:       client.id.String(),
+		Username: client.username,
+		Room:     client.room,
+	})
+
+	update := m.applyBehaviour(client.room, func(b RoomBehaviour) bool {
+		return b.Join(client)
+	})
+	go m.publishBehaviour(update)
 }
 
 func (m *Manager) removeClient(client *Client) {
+	if client.spectator {
+		m.removeSpectator(client)
+		return
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
-	m.db.UpdateSession(client.id)
-
 	if _, ok := m.Clients[client]; ok {
+		released := m.releaseObjects(client)
+		go m.publishObjects(released)
+		m.dropFollows(client)
+		go m.publishCrossings(m.leaveZones(client, time.Now()))
+
+		update := m.applyBehaviour(client.room, func(b RoomBehaviour) bool {
+			return b.Leave(client)
+		})
+		go m.publishBehaviour(update)
+
+		m.db.UpdateSession(client.session)
+		m.db.UpdateSessionStats(client.session, client.sessionStats())
+		m.db.SaveLastPosition(client.lastPosition())
+
 		client.conn.Close()
+		client.closeEgress()
 		delete(m.Clients, client)
+		m.linger(client, time.Now())
+		m.users.disconnect(client)
+		m.webhooks.dispatch(EventUserLeave, WebhookUser{
+			ID:       client.id.String(),
+			Username: client.username,
+			Room:     client.room,
+		})
+		if client.virtual {
+			m.forgetVirtual(client)
+		}
+		go m.broadcastRoomState(client.room)
+	}
+}
+
+// runCheckpoints periodically saves the session counters and positions of
+// connected clients, so they stay current for long sessions and survive a
+// crash.
+func (m *Manager) runCheckpoints(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.done:
+			return
+		}
+
+		for _, client := range m.snapshot() {
+			if err := m.db.UpdateSessionStats(client.session, client.sessionStats()); err != nil {
+				m.logger.Printf("error saving session stats: %v", err)
+			}
+
+			m.RLock()
+			position := client.lastPosition()
+			m.RUnlock()
+			if err := m.db.SaveLastPosition(position); err != nil {
+				m.logger.Printf("error saving last position: %v", err)
+			}
+		}
+	}
+}
+
+// snapshot returns the currently connected clients so they can be iterated
+// without holding the manager lock.
+func (m *Manager) snapshot() []*Client {
+	m.RLock()
+	defer m.RUnlock()
+
+	clients := make([]*Client, 0, len(m.Clients))
+	for client := range m.Clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// receivers returns the connected clients and spectators, everyone who should
+// get room broadcasts, so they can be iterated without holding the manager
+// lock.
+func (m *Manager) receivers() []*Client {
+	m.RLock()
+	defer m.RUnlock()
+
+	clients := make([]*Client, 0, len(m.Clients)+len(m.spectators))
+	for _, list := range []ClientList{m.Clients, m.spectators} {
+		for client := range list {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// broadcastRoomState sends everyone in the room the cursors they can see.
+func (m *Manager) broadcastRoomState(room string) {
+	for _, client := range m.receivers() {
+		if client.room == room {
+			broadcastState(client)
+		}
+	}
+}
+
+// broadcast sends the event to every client in the room for which include
+// returns true. A nil include sends to the whole room.
+func (m *Manager) broadcast(room string, event Event, include func(*Client) bool) {
+	for _, client := range m.receivers() {
+		if client.room != room {
+			continue
+		}
+		if include != nil && !include(client) {
+			continue
+		}
+		client.enqueue(event)
 	}
 }
 
 func (m *Manager) routeEvent(event Event, c *Client) error {
+	if c.spectator && !spectatorEvents[event.Type] {
+		return errSpectatorReadOnly
+	}
+	if c.isMuted() && !mutedEvents[event.Type] {
+		return errMuted
+	}
+	m.RLock()
 	handler, ok := m.handlers[event.Type]
+	m.RUnlock()
 	if !ok {
 		return errors.New("no handler for event type")
 	}
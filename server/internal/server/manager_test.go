@@ -0,0 +1,394 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// fakeDB is an in-memory stand-in for the database service. Methods that are
+// not overridden panic via the nil embedded interface. The manager writes to
+// it from background goroutines, such as webhook deliveries and object
+// saves, so every method holds mu.
+type fakeDB struct {
+	database.Service
+
+	mu sync.Mutex
+
+	objects   map[uuid.UUID]database.Object
+	strokes   map[uuid.UUID]database.Stroke
+	comments  map[uuid.UUID]database.Comment
+	reactions map[string]int64
+	zones     []database.Zone
+	polls     []database.Poll
+	visits    map[uuid.UUID]int64
+	scores    map[string]int64
+	positions map[string]database.LastPosition
+	rooms     map[string]database.Room
+	users     []database.User
+	bans      []database.Ban
+
+	webhooks    []database.Webhook
+	deliveries  []database.WebhookDelivery
+	deadLetters []database.WebhookDeadLetter
+}
+
+func (f *fakeDB) GetWebhooks() ([]database.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.webhooks, nil
+}
+
+func (f *fakeDB) GetWebhook(id uuid.UUID) (database.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, webhook := range f.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return database.Webhook{}, errors.New("webhook not found")
+}
+
+func (f *fakeDB) UpdateWebhook(webhook database.Webhook) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.webhooks {
+		if f.webhooks[i].ID == webhook.ID {
+			f.webhooks[i] = webhook
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) LogWebhookDelivery(delivery database.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries = append(f.deliveries, delivery)
+	return nil
+}
+
+func (f *fakeDB) CreateWebhookDeadLetter(letter database.WebhookDeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deadLetters = append(f.deadLetters, letter)
+	return nil
+}
+
+func (f *fakeDB) GetUser(username string) (database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, user := range f.users {
+		if user.Name == username {
+			return user, nil
+		}
+	}
+	return database.User{}, errors.New("user not found")
+}
+
+func (f *fakeDB) CreateUser(user database.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.users {
+		if existing.Name == user.Name {
+			return nil
+		}
+	}
+	f.users = append(f.users, user)
+	return nil
+}
+
+func (f *fakeDB) CreateSession(session database.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeDB) UpdateSession(sessionId uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeDB) UpdateSessionStats(sessionId uuid.UUID, stats database.SessionStats) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeDB) GetBlocks(blocker string) ([]database.Block, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return nil, nil
+}
+
+func (f *fakeDB) GetPolls(room string) ([]database.Poll, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return nil, nil
+}
+
+func (f *fakeDB) CreatePoll(poll database.Poll) (database.Poll, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls = append(f.polls, poll)
+	return poll, nil
+}
+
+func (f *fakeDB) GetUsers() ([]database.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.users, nil
+}
+
+func (f *fakeDB) GetLatestSession(username string) (database.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return database.Session{}, errors.New("session not found")
+}
+
+func (f *fakeDB) GetUserSessions() ([]database.UserSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var users []database.UserSession
+	for _, user := range f.users {
+		users = append(users, database.UserSession{User: user})
+	}
+	return users, nil
+}
+
+func (f *fakeDB) GetObjects(room string) ([]database.Object, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var objects []database.Object
+	for _, o := range f.objects {
+		if o.Room == room {
+			objects = append(objects, o)
+		}
+	}
+	return objects, nil
+}
+
+func (f *fakeDB) SaveObject(object database.Object) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.objects == nil {
+		f.objects = make(map[uuid.UUID]database.Object)
+	}
+	if stored, ok := f.objects[object.ID]; ok && stored.Seq >= object.Seq {
+		return nil
+	}
+	f.objects[object.ID] = object
+	return nil
+}
+
+func (f *fakeDB) GetStroke(id uuid.UUID) (database.Stroke, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stroke, ok := f.strokes[id]
+	if !ok {
+		return database.Stroke{}, errStrokeNotFound
+	}
+	return stroke, nil
+}
+
+func (f *fakeDB) CreateStroke(stroke database.Stroke) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.strokes == nil {
+		f.strokes = make(map[uuid.UUID]database.Stroke)
+	}
+	f.strokes[stroke.ID] = stroke
+	return nil
+}
+
+func (f *fakeDB) DeleteStroke(id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.strokes, id)
+	return nil
+}
+
+func (f *fakeDB) CreateComment(comment database.Comment) (database.Comment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.comments == nil {
+		f.comments = make(map[uuid.UUID]database.Comment)
+	}
+	f.comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (f *fakeDB) GetComment(id uuid.UUID) (database.Comment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	comment, ok := f.comments[id]
+	if !ok {
+		return database.Comment{}, errors.New("record not found")
+	}
+	return comment, nil
+}
+
+func (f *fakeDB) UpdateComment(comment database.Comment) (database.Comment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (f *fakeDB) IncrementReaction(room, emoji string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reactions == nil {
+		f.reactions = make(map[string]int64)
+	}
+	f.reactions[room+"/"+emoji]++
+	return nil
+}
+
+func (f *fakeDB) GetZones(room string) ([]database.Zone, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var zones []database.Zone
+	for _, z := range f.zones {
+		if z.Room == room {
+			zones = append(zones, z)
+		}
+	}
+	return zones, nil
+}
+
+func (f *fakeDB) GetZone(id uuid.UUID) (database.Zone, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, z := range f.zones {
+		if z.ID == id {
+			return z, nil
+		}
+	}
+	return database.Zone{}, errors.New("record not found")
+}
+
+func (f *fakeDB) DeleteZone(id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, z := range f.zones {
+		if z.ID == id {
+			f.zones = append(f.zones[:i], f.zones[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) RecordZoneVisit(zoneId uuid.UUID, dwell time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.visits == nil {
+		f.visits = make(map[uuid.UUID]int64)
+	}
+	f.visits[zoneId]++
+	return nil
+}
+
+func (f *fakeDB) AddGameScore(game, room, username string, delta int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.scores == nil {
+		f.scores = make(map[string]int64)
+	}
+	f.scores[game+"/"+username] += delta
+	return nil
+}
+
+func (f *fakeDB) GetLastPosition(username, room string) (database.LastPosition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	position, ok := f.positions[username+"/"+room]
+	if !ok {
+		return database.LastPosition{}, errors.New("record not found")
+	}
+	return position, nil
+}
+
+func (f *fakeDB) SaveLastPosition(position database.LastPosition) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.positions == nil {
+		f.positions = make(map[string]database.LastPosition)
+	}
+	f.positions[position.UserName+"/"+position.Room] = position
+	return nil
+}
+
+func (f *fakeDB) GetBans() ([]database.Ban, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bans, nil
+}
+
+func (f *fakeDB) GetRoom(name string) (database.Room, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	room, ok := f.rooms[name]
+	if !ok {
+		return database.Room{}, errors.New("record not found")
+	}
+	return room, nil
+}
+
+func (f *fakeDB) SaveRoom(room database.Room) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rooms == nil {
+		f.rooms = make(map[string]database.Room)
+	}
+	f.rooms[room.Name] = room
+	return nil
+}
+
+// newTestManager builds a manager through NewManager on an empty fakeDB, with
+// a small interest grid, short timeouts and any further options, and closes
+// it when the test ends.
+func newTestManager(t *testing.T, opts ...Option) *Manager {
+	t.Helper()
+	opts = append([]Option{
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithInterest(100, 0, 0),
+		WithGhosts(time.Second, 100*time.Millisecond),
+		WithVirtualClientTTL(time.Second),
+		WithPresenceInterval(10 * time.Millisecond),
+	}, opts...)
+
+	var db database.Service = &fakeDB{}
+	m := NewManager(&db, opts...)
+	t.Cleanup(func() { m.Close(context.Background()) })
+	return m
+}
+
+func addTestClient(m *Manager, x, y float64) *Client {
+	c := &Client{
+		id:      uuid.New(),
+		session: uuid.New(),
+		manager: m,
+		room:    DefaultRoom,
+		state:   State{X: x, Y: y},
+		trail:   newTrailBuffer(),
+		egress:  make(chan Event, 64),
+		strokes: make(map[uuid.UUID]*database.Stroke),
+
+		reactions: newRateLimiter(1, 2),
+		zones:     make(map[uuid.UUID]time.Time),
+	}
+	m.Clients[c] = true
+	m.gridFor(c.room).move(c, x, y)
+	return c
+}
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventObjectCreate  = "object_create"
+	EventObjectGrab    = "object_grab"
+	EventObjectMove    = "object_move"
+	EventObjectRelease = "object_release"
+	EventObjectUpdate  = "object_update"
+	EventObjectSync    = "object_sync"
+
+	// maxRoomObjects bounds the objects hosted in a room
+	maxRoomObjects = 256
+
+	// maxObjectText bounds an object's text, in bytes
+	maxObjectText = 1000
+)
+
+var (
+	errObjectNotFound = errors.New("object not found")
+	errObjectLocked   = errors.New("object is held by another client")
+	errTooManyObjects = errors.New("room has too many objects")
+	errObjectText     = errors.New("object text is too long")
+)
+
+// SharedObject is the server's authoritative state for an object hosted in a
+// room. Seq is taken from the manager's sequence on every change, so clients
+// apply an update only when its Seq is newer than the one they hold.
+type SharedObject struct {
+	ID        uuid.UUID `json:"id"`
+	Room      string    `json:"room"`
+	Kind      string    `json:"kind"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	Text      string    `json:"text"`
+	Color     int       `json:"color"`
+	Seq       int64     `json:"seq"`
+	CreatedBy string    `json:"created_by"`
+	HeldBy    string    `json:"held_by,omitempty"`
+
+	holder *Client
+}
+
+type ObjectEvent struct {
+	ID    uuid.UUID `json:"id"`
+	Kind  string    `json:"kind"`
+	X     float64   `json:"x"`
+	Y     float64   `json:"y"`
+	Text  string    `json:"text"`
+	Color int       `json:"color"`
+}
+
+func newSharedObject(o database.Object) *SharedObject {
+	return &SharedObject{
+		ID:        o.ID,
+		Room:      o.Room,
+		Kind:      o.Kind,
+		X:         o.X,
+		Y:         o.Y,
+		Text:      o.Text,
+		Color:     o.Color,
+		Seq:       o.Seq,
+		CreatedBy: o.CreatedBy,
+	}
+}
+
+func (o *SharedObject) record() database.Object {
+	return database.Object{
+		ID:        o.ID,
+		Room:      o.Room,
+		Kind:      o.Kind,
+		X:         o.X,
+		Y:         o.Y,
+		Text:      o.Text,
+		Color:     o.Color,
+		Seq:       o.Seq,
+		CreatedBy: o.CreatedBy,
+	}
+}
+
+// roomObjects returns the objects hosted in a room, loading them from the
+// database the first time the room is used. Callers must hold the manager lock.
+func (m *Manager) roomObjects(room string) map[uuid.UUID]*SharedObject {
+	objects, ok := m.objects[room]
+	if ok {
+		return objects
+	}
+
+	objects = make(map[uuid.UUID]*SharedObject)
+	records, err := m.db.GetObjects(room)
+	if err != nil {
+		m.logger.Printf("error loading objects for %s: %v", room, err)
+	}
+	for _, record := range records {
+		objects[record.ID] = newSharedObject(record)
+		if record.Seq > m.objectSeq {
+			m.objectSeq = record.Seq
+		}
+	}
+
+	m.objects[room] = objects
+	return objects
+}
+
+func (m *Manager) nextObjectSeq() int64 {
+	m.objectSeq++
+	return m.objectSeq
+}
+
+// changeObject applies fn to the object under the manager lock, stamps it with
+// a new sequence and broadcasts the result to the room, persisting it when fn
+// asks to. If fn fails the client is sent the current state so it can roll
+// back its local copy.
+func (m *Manager) changeObject(c *Client, id uuid.UUID, fn func(*SharedObject) (bool, error)) error {
+	m.Lock()
+	object, ok := m.roomObjects(c.room)[id]
+	if !ok {
+		m.Unlock()
+		return errObjectNotFound
+	}
+
+	persist, err := fn(object)
+	if err != nil {
+		current := *object
+		m.Unlock()
+		sendObject(c, current)
+		return err
+	}
+
+	object.Seq = m.nextObjectSeq()
+	current := *object
+	m.Unlock()
+
+	if persist {
+		if err := m.db.SaveObject(current.record()); err != nil {
+			m.logger.Printf("error saving object %s: %v", current.ID, err)
+		}
+	}
+
+	m.broadcastObject(current)
+	return nil
+}
+
+func (m *Manager) broadcastObject(object SharedObject) {
+	payload, err := json.Marshal(object)
+	if err != nil {
+		m.logger.Printf("failed to marshal object: %v", err)
+		return
+	}
+	m.broadcast(object.Room, Event{Type: EventObjectUpdate, Payload: payload}, nil)
+}
+
+func sendObject(c *Client, object SharedObject) {
+	payload, err := json.Marshal(object)
+	if err != nil {
+		c.manager.logger.Printf("failed to marshal object: %v", err)
+		return
+	}
+	c.enqueue(Event{Type: EventObjectUpdate, Payload: payload})
+}
+
+// releaseObjects frees every object held by the client, returning them so they
+// can be persisted and broadcast once the manager lock is released. Callers
+// must hold the manager lock.
+func (m *Manager) releaseObjects(c *Client) []SharedObject {
+	var released []SharedObject
+	for _, object := range m.objects[c.room] {
+		if object.holder == c {
+			object.holder = nil
+			object.HeldBy = ""
+			object.Seq = m.nextObjectSeq()
+			released = append(released, *object)
+		}
+	}
+	return released
+}
+
+func (m *Manager) publishObjects(objects []SharedObject) {
+	for _, object := range objects {
+		if err := m.db.SaveObject(object.record()); err != nil {
+			m.logger.Printf("error saving object %s: %v", object.ID, err)
+		}
+		m.broadcastObject(object)
+	}
+}
+
+// syncObjects sends a newly connected client every object in its room.
+func (m *Manager) syncObjects(c *Client) error {
+	m.Lock()
+	objects := []SharedObject{}
+	for _, object := range m.roomObjects(c.room) {
+		objects = append(objects, *object)
+	}
+	m.Unlock()
+
+	payload, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+
+	c.enqueue(Event{Type: EventObjectSync, Payload: payload})
+	return nil
+}
+
+func CreateObject(event Event, c *Client) error {
+	var create ObjectEvent
+	err := json.Unmarshal(event.Payload, &create)
+	if err != nil {
+		return err
+	}
+
+	if len(create.Text) > maxObjectText {
+		return errObjectText
+	}
+
+	m := c.manager
+	m.Lock()
+	objects := m.roomObjects(c.room)
+	if len(objects) >= maxRoomObjects {
+		m.Unlock()
+		return errTooManyObjects
+	}
+	object := &SharedObject{
+		ID:        uuid.New(),
+		Room:      c.room,
+		Kind:      create.Kind,
+		X:         create.X,
+		Y:         create.Y,
+		Text:      create.Text,
+		Color:     create.Color,
+		Seq:       m.nextObjectSeq(),
+		CreatedBy: c.username,
+	}
+	objects[object.ID] = object
+	current := *object
+	m.Unlock()
+
+	if err := m.db.SaveObject(current.record()); err != nil {
+		m.logger.Printf("error saving object %s: %v", current.ID, err)
+	}
+
+	m.broadcastObject(current)
+	return nil
+}
+
+// GrabObject locks the object to the client until it is released or the client
+// disconnects.
+func GrabObject(event Event, c *Client) error {
+	var grab ObjectEvent
+	err := json.Unmarshal(event.Payload, &grab)
+	if err != nil {
+		return err
+	}
+
+	return c.manager.changeObject(c, grab.ID, func(o *SharedObject) (bool, error) {
+		if o.holder != nil && o.holder != c {
+			return false, errObjectLocked
+		}
+		o.holder = c
+		o.HeldBy = c.username
+		return false, nil
+	})
+}
+
+// MoveObject moves an object that is free or held by the client. Concurrent
+// moves of a free object are resolved by arrival order: the last one the server
+// applies wins.
+func MoveObject(event Event, c *Client) error {
+	var move ObjectEvent
+	err := json.Unmarshal(event.Payload, &move)
+	if err != nil {
+		return err
+	}
+
+	return c.manager.changeObject(c, move.ID, func(o *SharedObject) (bool, error) {
+		if o.holder != nil && o.holder != c {
+			return false, errObjectLocked
+		}
+		o.X = move.X
+		o.Y = move.Y
+		// moves outside a grab are final, so persist them straight away
+		return o.holder == nil, nil
+	})
+}
+
+// ReleaseObject unlocks the object at its final position and persists it.
+func ReleaseObject(event Event, c *Client) error {
+	var release ObjectEvent
+	err := json.Unmarshal(event.Payload, &release)
+	if err != nil {
+		return err
+	}
+
+	return c.manager.changeObject(c, release.ID, func(o *SharedObject) (bool, error) {
+		if o.holder != c {
+			return false, errObjectLocked
+		}
+		o.holder = nil
+		o.HeldBy = ""
+		o.X = release.X
+		o.Y = release.Y
+		return true, nil
+	})
+}
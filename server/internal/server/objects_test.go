@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func objectPayload(id uuid.UUID, x, y float64) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"id":%q,"x":%v,"y":%v}`, id, x, y))
+}
+
+func TestObjectLock(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	bob := addTestClient(m, 0, 0)
+
+	err := CreateObject(Event{Type: EventObjectCreate, Payload: json.RawMessage(`{"kind":"sticky","x":1,"y":2}`)}, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id uuid.UUID
+	for objectID := range m.objects[DefaultRoom] {
+		id = objectID
+	}
+
+	if err := GrabObject(Event{Type: EventObjectGrab, Payload: objectPayload(id, 0, 0)}, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MoveObject(Event{Type: EventObjectMove, Payload: objectPayload(id, 50, 50)}, bob); err != errObjectLocked {
+		t.Fatalf("expected move by another client to be rejected, got %v", err)
+	}
+
+	if err := ReleaseObject(Event{Type: EventObjectRelease, Payload: objectPayload(id, 10, 20)}, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := m.db.(*fakeDB).objects[id]
+	if saved.X != 10 || saved.Y != 20 {
+		t.Fatalf("expected released position to be persisted, got (%v, %v)", saved.X, saved.Y)
+	}
+
+	if err := MoveObject(Event{Type: EventObjectMove, Payload: objectPayload(id, 50, 50)}, bob); err != nil {
+		t.Fatalf("expected move of a free object to succeed, got %v", err)
+	}
+
+	object := m.objects[DefaultRoom][id]
+	if object.X != 50 || object.Seq <= saved.Seq {
+		t.Fatalf("expected later move to win with a newer sequence, got x=%v seq=%d", object.X, object.Seq)
+	}
+}
+
+func TestReleaseObjectsOnDisconnect(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+
+	id := uuid.New()
+	m.objects[DefaultRoom] = map[uuid.UUID]*SharedObject{
+		id: {ID: id, Room: DefaultRoom, holder: alice, HeldBy: alice.username},
+	}
+
+	released := m.releaseObjects(alice)
+	if len(released) != 1 || m.objects[DefaultRoom][id].holder != nil {
+		t.Fatal("expected the held object to be released")
+	}
+}
+
+func TestObjectLimits(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+
+	long := fmt.Sprintf(`{"kind":"sticky","text":%q}`, strings.Repeat("a", maxObjectText+1))
+	if err := CreateObject(Event{Type: EventObjectCreate, Payload: json.RawMessage(long)}, alice); err != errObjectText {
+		t.Fatalf("expected overlong text to be rejected, got %v", err)
+	}
+
+	objects := m.roomObjects(DefaultRoom)
+	for len(objects) < maxRoomObjects {
+		id := uuid.New()
+		objects[id] = &SharedObject{ID: id, Room: DefaultRoom}
+	}
+	if err := CreateObject(Event{Type: EventObjectCreate, Payload: json.RawMessage(`{"kind":"sticky"}`)}, alice); err != errTooManyObjects {
+		t.Fatalf("expected a full room to refuse new objects, got %v", err)
+	}
+}
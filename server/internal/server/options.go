@@ -0,0 +1,236 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Authenticator identifies the user behind a connection request. When set it
+// replaces the username query parameter; returning an error refuses the
+// connection.
+type Authenticator func(r *http.Request) (username string, err error)
+
+// Option configures a Manager.
+type Option func(*options)
+
+type options struct {
+	logger         *log.Logger
+	auth           Authenticator
+	trustedProxies []string
+	adminToken     string
+	ingestKeys     []string
+
+	cellSize       float64
+	interestMargin float64
+	maxCursors     int
+
+	reactionRate  float64
+	reactionBurst int
+	reactionTTL   time.Duration
+
+	webhookAllowPrivate bool
+	webhookAttempts     int
+	webhookBackoff      time.Duration
+	webhookMaxBackoff   time.Duration
+
+	virtualTTL time.Duration
+
+	usernameMin int
+	usernameMax int
+	denyWords   []string
+
+	ghostTTL   time.Duration
+	ghostDecay time.Duration
+
+	trailWindow time.Duration
+	trailRate   int
+
+	presenceInterval   time.Duration
+	tagRadius          float64
+	checkpointInterval time.Duration
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		logger: log.Default(),
+
+		cellSize:       256,
+		interestMargin: 200,
+
+		reactionRate:  2,
+		reactionBurst: 5,
+		reactionTTL:   2 * time.Second,
+
+		webhookAttempts:   5,
+		webhookBackoff:    500 * time.Millisecond,
+		webhookMaxBackoff: time.Minute,
+
+		virtualTTL: 30 * time.Second,
+
+		usernameMin: 2,
+		usernameMax: 24,
+
+		ghostTTL:   10 * time.Second,
+		ghostDecay: 400 * time.Millisecond,
+
+		trailWindow: 5 * time.Second,
+		trailRate:   120,
+
+		presenceInterval:   time.Second,
+		tagRadius:          16,
+		checkpointInterval: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLogger sends the manager's logs to logger instead of the standard
+// logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithAuthenticator makes connections authenticate with auth rather than name
+// themselves with ?username=. The usernames it returns are taken as they are,
+// without the rules for usernames clients choose themselves.
+func WithAuthenticator(auth Authenticator) Option {
+	return func(o *options) {
+		o.auth = auth
+	}
+}
+
+// WithTrustedProxies lists the addresses or CIDR ranges of the proxies whose
+// X-Forwarded-For headers are believed when the manager serves its own
+// routes. By default no proxy is trusted and client addresses, which bans
+// match on, are the connecting peer's.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(o *options) {
+		o.trustedProxies = proxies
+	}
+}
+
+// WithAdminToken enables the /admin API for requests carrying token as a
+// bearer token. The admin API is disabled without one.
+func WithAdminToken(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
+
+// WithIngestKeys enables the position ingestion API for requests carrying one
+// of keys in the X-API-Key header. It is disabled without keys.
+func WithIngestKeys(keys ...string) Option {
+	return func(o *options) {
+		o.ingestKeys = keys
+	}
+}
+
+// WithInterest sets the cell size of the spatial index, how far beyond a
+// client's viewport cursors are still sent, and the most cursors a client is
+// sent, with 0 for no limit. The defaults are 256, 200 and no limit.
+func WithInterest(cellSize, margin float64, maxCursors int) Option {
+	return func(o *options) {
+		o.cellSize = cellSize
+		o.interestMargin = margin
+		o.maxCursors = maxCursors
+	}
+}
+
+// WithReactions limits each client to rate reactions a second, with bursts of
+// up to burst, and keeps reactions on the canvas for ttl. The defaults are 2,
+// 5 and two seconds.
+func WithReactions(rate float64, burst int, ttl time.Duration) Option {
+	return func(o *options) {
+		o.reactionRate = rate
+		o.reactionBurst = burst
+		o.reactionTTL = ttl
+	}
+}
+
+// WithWebhookRetries makes up to attempts deliveries of each webhook event,
+// waiting backoff before the first retry and doubling it up to maxBackoff. The
+// defaults are 5 attempts, half a second and a minute.
+func WithWebhookRetries(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.webhookAttempts = attempts
+		o.webhookBackoff = backoff
+		o.webhookMaxBackoff = maxBackoff
+	}
+}
+
+// WithPrivateWebhooks lets webhooks target loopback, link-local and private
+// addresses, which are refused by default.
+func WithPrivateWebhooks(allow bool) Option {
+	return func(o *options) {
+		o.webhookAllowPrivate = allow
+	}
+}
+
+// WithVirtualClientTTL disconnects virtual clients that haven't been updated
+// within ttl, 30 seconds by default.
+func WithVirtualClientTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.virtualTTL = ttl
+	}
+}
+
+// WithUsernameRules sets the length limits of the usernames clients choose,
+// 2 and 24 by default, and words they may not contain.
+func WithUsernameRules(minLength, maxLength int, denyWords ...string) Option {
+	return func(o *options) {
+		o.usernameMin = minLength
+		o.usernameMax = maxLength
+		o.denyWords = denyWords
+	}
+}
+
+// WithGhosts keeps disconnected cursors drifting for ttl, slowing with the
+// decay time constant. The defaults are 10 seconds and 400 milliseconds; a ttl
+// of 0 removes cursors straight away.
+func WithGhosts(ttl, decay time.Duration) Option {
+	return func(o *options) {
+		o.ghostTTL = ttl
+		o.ghostDecay = decay
+	}
+}
+
+// WithTrails sets how much of each cursor's trail is kept in rooms that don't
+// set their own window, and the most trail points kept for each second of it.
+// The defaults are 5 seconds and 120.
+func WithTrails(window time.Duration, maxRate int) Option {
+	return func(o *options) {
+		o.trailWindow = window
+		o.trailRate = maxRate
+	}
+}
+
+// WithPresenceInterval sets how often presence streams check for changes,
+// every second by default.
+func WithPresenceInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.presenceInterval = interval
+	}
+}
+
+// WithTagRadius sets the radius of the circle each cursor is treated as in
+// games of tag, 16 by default.
+func WithTagRadius(radius float64) Option {
+	return func(o *options) {
+		o.tagRadius = radius
+	}
+}
+
+// WithCheckpointInterval sets how often connected cursors' positions and
+// stats are saved, every 30 seconds by default.
+func WithCheckpointInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.checkpointInterval = interval
+	}
+}
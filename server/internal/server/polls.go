@@ -0,0 +1,241 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventPollResults = "poll_results"
+
+	// defaultPollDwell is how long a cursor must stay in an option's zone
+	// before it counts as a vote
+	defaultPollDwell = 1500 * time.Millisecond
+	pollTick         = 250 * time.Millisecond
+)
+
+var errUnknownPollZone = errors.New("poll option refers to an unknown zone")
+
+// Poll is an open poll whose votes are cast by hovering in an option's zone.
+// Each user has a single vote, which moves to whichever option they last
+// dwelt in.
+type Poll struct {
+	record database.Poll
+	votes  map[string]uuid.UUID
+}
+
+type PollResult struct {
+	ID          uuid.UUID          `json:"id"`
+	Room        string             `json:"room"`
+	Question    string             `json:"question"`
+	Facilitator string             `json:"facilitator"`
+	DwellMs     int64              `json:"dwell_ms"`
+	Closed      bool               `json:"closed"`
+	Options     []PollOptionResult `json:"options"`
+	Total       int                `json:"total"`
+}
+
+type PollOptionResult struct {
+	Label  string    `json:"label"`
+	ZoneID uuid.UUID `json:"zone_id"`
+	Votes  int       `json:"votes"`
+}
+
+func pollResults(poll database.Poll, votes map[string]uuid.UUID) PollResult {
+	counts := make(map[uuid.UUID]int)
+	for _, zoneId := range votes {
+		counts[zoneId]++
+	}
+
+	result := PollResult{
+		ID:          poll.ID,
+		Room:        poll.Room,
+		Question:    poll.Question,
+		Facilitator: poll.Facilitator,
+		DwellMs:     poll.DwellMs,
+		Closed:      poll.Closed,
+		Options:     []PollOptionResult{},
+		Total:       len(votes),
+	}
+	for _, option := range poll.Options {
+		result.Options = append(result.Options, PollOptionResult{
+			Label:  option.Label,
+			ZoneID: option.ZoneID,
+			Votes:  counts[option.ZoneID],
+		})
+	}
+	return result
+}
+
+// NewPollRequest is the body of a new poll. The user making the request
+// facilitates it.
+type NewPollRequest struct {
+	Question string                `json:"question" binding:"required"`
+	DwellMs  int64                 `json:"dwell_ms"`
+	Options  []database.PollOption `json:"options" binding:"required,min=2"`
+}
+
+type UpdatePollRequest struct {
+	Closed *bool `json:"closed"`
+}
+
+func (m *Manager) loadVotes(pollId uuid.UUID) map[string]uuid.UUID {
+	votes := make(map[string]uuid.UUID)
+	records, err := m.db.GetPollVotes(pollId)
+	if err != nil {
+		m.logger.Printf("error loading votes for %s: %v", pollId, err)
+	}
+	for _, record := range records {
+		votes[record.UserName] = record.ZoneID
+	}
+	return votes
+}
+
+// roomPolls returns the open polls in a room, loading them from the database
+// the first time the room is used. Callers must hold the manager lock.
+func (m *Manager) roomPolls(room string) map[uuid.UUID]*Poll {
+	polls, ok := m.polls[room]
+	if ok {
+		return polls
+	}
+
+	polls = make(map[uuid.UUID]*Poll)
+	records, err := m.db.GetPolls(room)
+	if err != nil {
+		m.logger.Printf("error loading polls for %s: %v", room, err)
+	}
+	for _, record := range records {
+		if !record.Closed {
+			polls[record.ID] = &Poll{record: record, votes: m.loadVotes(record.ID)}
+		}
+	}
+
+	m.polls[room] = polls
+	return polls
+}
+
+// validatePoll checks that every option maps to a zone in the room.
+func (m *Manager) validatePoll(room string, options []database.PollOption) error {
+	m.Lock()
+	defer m.Unlock()
+
+	zones := m.roomZones(room)
+	for _, option := range options {
+		if _, ok := zones[option.ZoneID]; !ok {
+			return errUnknownPollZone
+		}
+	}
+	return nil
+}
+
+func (m *Manager) addPoll(record database.Poll) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.roomPolls(record.Room)[record.ID] = &Poll{
+		record: record,
+		votes:  make(map[string]uuid.UUID),
+	}
+}
+
+// closePoll stops counting votes for the poll and pushes the final results.
+func (m *Manager) closePoll(record database.Poll) {
+	m.Lock()
+	polls := m.roomPolls(record.Room)
+	poll, ok := polls[record.ID]
+	delete(polls, record.ID)
+	m.Unlock()
+
+	if ok {
+		m.broadcastPoll(pollResults(record, poll.votes))
+	}
+}
+
+func (m *Manager) broadcastPoll(result PollResult) {
+	event, err := NewEvent(EventPollResults, result)
+	if err != nil {
+		m.logger.Printf("failed to marshal poll results: %v", err)
+		return
+	}
+	m.broadcast(result.Room, event, nil)
+}
+
+// tallyPolls casts a vote for every client that has dwelt long enough in one
+// of an open poll's zones. It returns the changed votes and the new results of
+// each poll they belong to. Callers must hold the manager lock.
+func (m *Manager) tallyPolls(now time.Time) ([]database.PollVote, []PollResult) {
+	var votes []database.PollVote
+	var results []PollResult
+
+	for client := range m.Clients {
+		for _, poll := range m.polls[client.room] {
+			dwell := time.Duration(poll.record.DwellMs) * time.Millisecond
+			if dwell <= 0 {
+				dwell = defaultPollDwell
+			}
+
+			// with overlapping zones the one entered most recently wins
+			var choice uuid.UUID
+			var latest time.Time
+			for _, option := range poll.record.Options {
+				enteredAt, ok := client.zones[option.ZoneID]
+				if ok && now.Sub(enteredAt) >= dwell && enteredAt.After(latest) {
+					choice, latest = option.ZoneID, enteredAt
+				}
+			}
+			if choice == uuid.Nil {
+				continue
+			}
+			if current, ok := poll.votes[client.username]; ok && current == choice {
+				continue
+			}
+
+			poll.votes[client.username] = choice
+			votes = append(votes, database.PollVote{
+				PollID:   poll.record.ID,
+				UserName: client.username,
+				ZoneID:   choice,
+			})
+			results = append(results, pollResults(poll.record, poll.votes))
+		}
+	}
+	return votes, results
+}
+
+// runPolls periodically tallies open polls so a cursor resting in a zone is
+// counted without needing further position updates.
+func (m *Manager) runPolls() {
+	ticker := time.NewTicker(pollTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.done:
+			return
+		}
+
+		m.Lock()
+		votes, results := m.tallyPolls(time.Now())
+		m.Unlock()
+
+		for _, vote := range votes {
+			if err := m.db.SavePollVote(vote); err != nil {
+				m.logger.Printf("error saving vote: %v", err)
+			}
+		}
+
+		// only the latest results of each poll need to go out
+		latest := make(map[uuid.UUID]PollResult)
+		for _, result := range results {
+			latest[result.ID] = result
+		}
+		for _, result := range latest {
+			m.broadcastPoll(result)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestTallyPolls(t *testing.T) {
+	m := newTestManager(t)
+	yes, no := uuid.New(), uuid.New()
+	record := database.Poll{
+		ID:      uuid.New(),
+		Room:    DefaultRoom,
+		DwellMs: 1000,
+		Options: []database.PollOption{{Label: "yes", ZoneID: yes}, {Label: "no", ZoneID: no}},
+	}
+	m.polls[DefaultRoom] = map[uuid.UUID]*Poll{
+		record.ID: {record: record, votes: make(map[string]uuid.UUID)},
+	}
+
+	now := time.Now()
+	alice := addTestClient(m, 0, 0)
+	alice.username = "alice"
+	alice.zones[yes] = now.Add(-2 * time.Second)
+	bob := addTestClient(m, 0, 0)
+	bob.username = "bob"
+	bob.zones[no] = now.Add(-500 * time.Millisecond)
+
+	votes, results := m.tallyPolls(now)
+	if len(votes) != 1 || votes[0].UserName != "alice" {
+		t.Fatalf("expected only alice to have dwelt long enough, got %+v", votes)
+	}
+	if results[0].Options[0].Votes != 1 || results[0].Options[1].Votes != 0 {
+		t.Fatalf("unexpected results: %+v", results[0])
+	}
+
+	if votes, _ := m.tallyPolls(now); len(votes) != 0 {
+		t.Fatal("expected an unchanged vote not to be cast again")
+	}
+
+	votes, results = m.tallyPolls(now.Add(time.Second))
+	if len(votes) != 1 || votes[0].UserName != "bob" {
+		t.Fatalf("expected bob's vote once he has dwelt, got %+v", votes)
+	}
+	if results[0].Total != 2 {
+		t.Fatalf("expected 2 votes in total, got %d", results[0].Total)
+	}
+}
+
+func TestCreatePollFacilitator(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	alice.username, alice.token = "alice", "alice-token"
+	db := m.db.(*fakeDB)
+	yes, no := uuid.New(), uuid.New()
+	db.zones = []database.Zone{{ID: yes, Room: DefaultRoom}, {ID: no, Room: DefaultRoom}}
+
+	s := &Server{db: db, manager: m}
+	r := gin.New()
+	r.POST("/rooms/:room/polls", s.createPollHandler)
+	body := fmt.Sprintf(`{"question":"ship it?","facilitator":"mallory","options":[{"label":"yes","zone_id":%q},{"label":"no","zone_id":%q}]}`, yes, no)
+	create := func(token string) int {
+		req := httptest.NewRequest("POST", "/rooms/lobby/polls", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(SessionTokenHeader, token)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := create(""); code != http.StatusUnauthorized {
+		t.Fatalf("expected a poll without a session to be refused, got %d", code)
+	}
+	if code := create(alice.token); code != http.StatusCreated {
+		t.Fatalf("expected alice to create a poll, got %d", code)
+	}
+	if len(db.polls) != 1 || db.polls[0].Facilitator != "alice" {
+		t.Fatalf("expected alice to facilitate the poll, got %+v", db.polls)
+	}
+}
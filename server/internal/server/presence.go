@@ -0,0 +1,167 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const EventPresence = "presence"
+
+type PresenceClient struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Color    int    `json:"color"`
+	Mood     string `json:"mood"`
+	State    State  `json:"state"`
+}
+
+// RoomPresence is the live roster of a room.
+type RoomPresence struct {
+	Room       string           `json:"room"`
+	Clients    []PresenceClient `json:"clients"`
+	Spectators int              `json:"spectators"`
+}
+
+// etag identifies the presence's content, so unchanged rosters can be
+// answered with 304 and skipped on the stream.
+func (p RoomPresence) etag() (string, []byte, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, body, nil
+}
+
+// presence returns the live roster of every room, or only of the given room
+// when it isn't empty. Clients are ordered by username so the result is stable.
+func (m *Manager) presence(room string) map[string]*RoomPresence {
+	m.RLock()
+	defer m.RUnlock()
+
+	rooms := make(map[string]*RoomPresence)
+	roomFor := func(name string) *RoomPresence {
+		p, ok := rooms[name]
+		if !ok {
+			p = &RoomPresence{Room: name, Clients: []PresenceClient{}}
+			rooms[name] = p
+		}
+		return p
+	}
+	if room != "" {
+		roomFor(room)
+	}
+
+	for client := range m.Clients {
+		if room != "" && client.room != room {
+			continue
+		}
+		p := roomFor(client.room)
+		p.Clients = append(p.Clients, PresenceClient{
+			ID:       client.id.String(),
+			Username: client.username,
+			Color:    client.color,
+			Mood:     client.mood,
+			State:    client.state,
+		})
+	}
+	for client := range m.spectators {
+		if room == "" || client.room == room {
+			roomFor(client.room).Spectators++
+		}
+	}
+
+	for _, p := range rooms {
+		sort.Slice(p.Clients, func(i, j int) bool {
+			if p.Clients[i].Username != p.Clients[j].Username {
+				return p.Clients[i].Username < p.Clients[j].Username
+			}
+			return p.Clients[i].ID < p.Clients[j].ID
+		})
+	}
+	return rooms
+}
+
+func (s *Server) getPresenceHandler(c *gin.Context) {
+	room := c.Param("room")
+	presence := s.manager.presence(room)[room]
+
+	etag, body, err := presence.etag()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// presenceStreamHandler streams presence as Server-Sent Events. Every room's
+// roster is sent on connect, then again whenever it changes, checked every
+// presenceInterval. A room that empties is sent once with no clients. The
+// stream can be limited to one room with ?room=.
+func (s *Server) presenceStreamHandler(c *gin.Context) {
+	room := c.Query("room")
+
+	// the server's write timeout is meant for ordinary requests and would
+	// cut the stream off after a few seconds
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		s.manager.logger.Printf("failed to clear the presence stream deadline: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(s.manager.presenceInterval)
+	defer ticker.Stop()
+
+	sent := make(map[string]string)
+	for {
+		rooms := s.manager.presence(room)
+		for name := range sent {
+			if _, ok := rooms[name]; !ok {
+				rooms[name] = &RoomPresence{Room: name, Clients: []PresenceClient{}}
+			}
+		}
+
+		for name, presence := range rooms {
+			etag, body, err := presence.etag()
+			if err != nil {
+				s.manager.logger.Printf("failed to marshal presence: %v", err)
+				continue
+			}
+			if sent[name] == etag {
+				continue
+			}
+
+			c.SSEvent(EventPresence, json.RawMessage(body))
+			if len(presence.Clients) == 0 && presence.Spectators == 0 && name != room {
+				delete(sent, name)
+			} else {
+				sent[name] = etag
+			}
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-ticker.C:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
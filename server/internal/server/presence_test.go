@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetPresenceHandler(t *testing.T) {
+	m := newTestManager(t)
+	client := addTestClient(m, 12, 34)
+	client.username = "alice"
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.GET("/rooms/:room/presence", s.getPresenceHandler)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/rooms/lobby/presence", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+
+	var presence RoomPresence
+	if err := json.Unmarshal(rr.Body.Bytes(), &presence); err != nil {
+		t.Fatal(err)
+	}
+	if len(presence.Clients) != 1 || presence.Clients[0].Username != "alice" || presence.Clients[0].State.X != 12 {
+		t.Fatalf("unexpected presence: %+v", presence)
+	}
+
+	etag := rr.Header().Get("ETag")
+	req := httptest.NewRequest("GET", "/rooms/lobby/presence", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for an unchanged roster, got %d", rr.Code)
+	}
+
+	m.Lock()
+	client.state.X = 50
+	m.Unlock()
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") == etag {
+		t.Fatalf("expected a new ETag after the cursor moved, got %d", rr.Code)
+	}
+}
+
+func TestPresenceStreamHandler(t *testing.T) {
+	m := newTestManager(t)
+	client := addTestClient(m, 0, 0)
+	client.username = "alice"
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.GET("/presence/stream", s.presenceStreamHandler)
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/presence/stream?room=lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	readPresence := func() RoomPresence {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:"); ok {
+				var presence RoomPresence
+				if err := json.Unmarshal([]byte(data), &presence); err != nil {
+					t.Fatal(err)
+				}
+				return presence
+			}
+		}
+	}
+
+	if presence := readPresence(); len(presence.Clients) != 1 {
+		t.Fatalf("expected alice in the first event, got %+v", presence)
+	}
+
+	// the stream outlives the server's write timeout
+	time.Sleep(100 * time.Millisecond)
+
+	m.Lock()
+	delete(m.Clients, client)
+	m.Unlock()
+
+	if presence := readPresence(); len(presence.Clients) != 0 {
+		t.Fatalf("expected an empty room after alice left, got %+v", presence)
+	}
+}
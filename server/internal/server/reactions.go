@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	EventReaction = "reaction"
+
+	// maxReactionLength bounds the emoji sequence in a reaction, in bytes
+	maxReactionLength = 32
+)
+
+var (
+	errInvalidReaction = errors.New("reaction must be a single emoji")
+	errRateLimited     = errors.New("rate limit exceeded")
+)
+
+type ReactionEvent struct {
+	Emoji string `json:"emoji"`
+}
+
+type Reaction struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Emoji     string    `json:"emoji"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// React fires a short-lived emoji burst at the client's current position. It
+// is broadcast to the room with an expiry after which clients should remove
+// it, and counted towards the room's reaction totals.
+func React(event Event, c *Client) error {
+	var react ReactionEvent
+	err := json.Unmarshal(event.Payload, &react)
+	if err != nil {
+		return err
+	}
+
+	if len(react.Emoji) > maxReactionLength || !singleEmoji(react.Emoji) {
+		return errInvalidReaction
+	}
+	if !c.reactions.allow() {
+		return errRateLimited
+	}
+
+	m := c.manager
+	m.RLock()
+	reaction := Reaction{
+		ID:        c.id.String(),
+		Username:  c.username,
+		Emoji:     react.Emoji,
+		X:         c.state.X,
+		Y:         c.state.Y,
+		ExpiresAt: time.Now().Add(m.reactionTTL),
+	}
+	m.RUnlock()
+
+	payload, err := json.Marshal(reaction)
+	if err != nil {
+		return err
+	}
+
+	m.broadcast(c.room, Event{Type: EventReaction, Payload: payload}, m.unblocked(c.username, func(client *Client) bool {
+		m.RLock()
+		defer m.RUnlock()
+		return m.interested(client, reaction.X, reaction.Y)
+	}))
+
+	if err := m.db.IncrementReaction(c.room, react.Emoji); err != nil {
+		m.logger.Printf("error counting reaction: %v", err)
+	}
+	return nil
+}
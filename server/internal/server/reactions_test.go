@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(1, 2)
+	now := l.last
+
+	if !l.allowAt(now) || !l.allowAt(now) {
+		t.Fatal("expected burst to be allowed")
+	}
+	if l.allowAt(now) {
+		t.Fatal("expected limiter to be exhausted")
+	}
+	if !l.allowAt(now.Add(time.Second)) {
+		t.Fatal("expected a token to be refilled after a second")
+	}
+}
+
+func TestReactionRateLimit(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 5, 5)
+	event := Event{Type: EventReaction, Payload: json.RawMessage(`{"emoji":"🎉"}`)}
+
+	for i := 0; i < 2; i++ {
+		if err := React(event, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := React(event, c); err != errRateLimited {
+		t.Fatalf("expected third reaction to be rate limited, got %v", err)
+	}
+
+	if count := m.db.(*fakeDB).reactions[DefaultRoom+"/🎉"]; count != 2 {
+		t.Fatalf("expected 2 reactions to be counted, got %d", count)
+	}
+
+	var reaction Reaction
+	if err := json.Unmarshal((<-c.egress).Payload, &reaction); err != nil {
+		t.Fatal(err)
+	}
+	if reaction.X != 5 || reaction.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected reaction: %+v", reaction)
+	}
+}
+
+func TestReactionMustBeEmoji(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	for _, emoji := range []string{"", "lol", "🎉🎉", "🎉!"} {
+		payload, _ := json.Marshal(ReactionEvent{Emoji: emoji})
+		if err := React(Event{Type: EventReaction, Payload: payload}, c); err != errInvalidReaction {
+			t.Fatalf("%q: expected errInvalidReaction, got %v", emoji, err)
+		}
+	}
+	if len(m.db.(*fakeDB).reactions) != 0 {
+		t.Fatal("expected rejected reactions not to be counted")
+	}
+}
@@ -1,27 +1,102 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
 	r := gin.Default()
+	s.manager.trustProxies(r)
 
 	r.GET("/", s.HelloWorldHandler)
 
 	r.GET("/health", s.healthHandler)
 
+	s.mount(r)
+
+	return r
+}
+
+// mount registers the hub's routes: everything but the hello world and health
+// checks of the standalone server.
+func (s *Server) mount(r gin.IRouter) {
 	r.GET("/users", s.getUsersHandler)
 
 	r.GET("/users/:username", s.getUserHandler)
 
 	r.PATCH("/users/:username", s.updateUserHandler)
 
+	r.GET("/users/:username/stats", s.getUserStatsHandler)
+
+	r.GET("/users/:username/blocks", s.manager.userAuth, s.getBlocksHandler)
+
+	r.POST("/users/:username/blocks", s.manager.userAuth, s.createBlockHandler)
+
+	r.DELETE("/users/:username/blocks", s.manager.userAuth, s.deleteBlockHandler)
+
+	r.GET("/leaderboards/:metric", s.getLeaderboardHandler)
+
+	r.GET("/rooms/:room/presence", s.getPresenceHandler)
+
+	r.GET("/presence/stream", s.presenceStreamHandler)
+
+	r.GET("/rooms/:room/trail", s.getTrailSettingsHandler)
+
+	r.PUT("/rooms/:room/trail", s.manager.callerAuth, s.updateTrailSettingsHandler)
+
+	r.GET("/rooms/:room/strokes", s.getStrokesHandler)
+
+	r.GET("/rooms/:room/comments", s.getCommentsHandler)
+
+	r.POST("/rooms/:room/comments", s.createCommentHandler)
+
+	r.PATCH("/rooms/:room/comments/:id", s.manager.callerAuth, s.updateCommentHandler)
+
+	r.GET("/rooms/:room/reactions", s.getReactionsHandler)
+
+	r.GET("/rooms/:room/zones", s.getZonesHandler)
+
+	r.POST("/rooms/:room/zones", s.manager.callerAuth, s.createZoneHandler)
+
+	r.DELETE("/rooms/:room/zones/:id", s.manager.callerAuth, s.deleteZoneHandler)
+
+	r.GET("/rooms/:room/zones/:id/stats", s.getZoneStatsHandler)
+
+	r.GET("/rooms/:room/polls", s.getPollsHandler)
+
+	r.POST("/rooms/:room/polls", s.createPollHandler)
+
+	r.GET("/rooms/:room/polls/:id", s.getPollHandler)
+
+	r.PATCH("/rooms/:room/polls/:id", s.manager.callerAuth, s.updatePollHandler)
+
+	r.GET("/rooms/:room/behaviour", s.getBehaviourHandler)
+
+	r.PUT("/rooms/:room/behaviour", s.manager.callerAuth, s.startBehaviourHandler)
+
+	r.DELETE("/rooms/:room/behaviour", s.manager.callerAuth, s.stopBehaviourHandler)
+
+	r.GET("/games/:game/scores", s.getGameScoresHandler)
+
 	r.GET("/ws", s.manager.initiateWSConnection)
 
-	return r
+	r.GET("/ws/users", s.manager.initiateUsersFeed)
+
+	r.GET("/sse", s.manager.initiateSSEConnection)
+
+	r.POST("/sse", s.manager.postSSEEventHandler)
+
+	s.registerAdminRoutes(r)
+
+	s.registerIngestRoutes(r)
 }
 
 func (s *Server) HelloWorldHandler(c *gin.Context) {
@@ -36,11 +111,34 @@ func (s *Server) healthHandler(c *gin.Context) {
 }
 
 type User struct {
-	Username      string `json:"username"`
-	Color         int    `json:"color"`
-	Mood          string `json:"mood"`
-	IsActive      bool   `json:"is_active"`
-	LastSessionId string `json:"last_session_id"`
+	Username      string         `json:"username"`
+	Color         int            `json:"color"`
+	Mood          string         `json:"mood"`
+	IsActive      bool           `json:"is_active"`
+	LastSessionId string         `json:"last_session_id"`
+	LastPositions []LastPosition `json:"last_positions,omitempty"`
+}
+
+type LastPosition struct {
+	Room      string     `json:"room"`
+	State     State      `json:"state"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func newLastPosition(p database.LastPosition) LastPosition {
+	return LastPosition{
+		Room: p.Room,
+		State: State{
+			X:   p.X,
+			Y:   p.Y,
+			Vx:  p.Vx,
+			Vy:  p.Vy,
+			Spd: p.Spd,
+			Acc: p.Acc,
+			Ang: p.Ang,
+		},
+		UpdatedAt: p.UpdatedAt,
+	}
 }
 
 func (s *Server) getUsersHandler(c *gin.Context) {
@@ -84,12 +182,22 @@ func (s *Server) getUserHandler(c *gin.Context) {
 		return
 	}
 
+	positions, err := s.db.GetLastPositions(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	user := User{
 		Username:      session.UserName,
 		Color:         client.Color,
 		Mood:          client.Mood,
 		IsActive:      session.IsActive,
 		LastSessionId: session.ID.String(),
+		LastPositions: []LastPosition{},
+	}
+	for _, position := range positions {
+		user.LastPositions = append(user.LastPositions, newLastPosition(position))
 	}
 
 	c.JSON(http.StatusOK, user)
@@ -103,6 +211,12 @@ func (s *Server) updateUserHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if user.Mood != "" {
+		if err := validMood(user.Mood); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	client, err := s.db.GetUser(username)
 	if err != nil {
@@ -114,6 +228,7 @@ func (s *Server) updateUserHandler(c *gin.Context) {
 	if user.Color != 0 {
 		client.Color = user.Color
 	}
+	previousMood := client.Mood
 	if user.Mood != "" {
 		client.Mood = user.Mood
 	}
@@ -123,5 +238,703 @@ func (s *Server) updateUserHandler(c *gin.Context) {
 		return
 	}
 
+	s.manager.updateProfile(client)
+	if client.Mood != previousMood {
+		s.manager.webhooks.dispatch(EventMoodChange, MoodChange{
+			Username: client.Name,
+			Mood:     client.Mood,
+			Previous: previousMood,
+		})
+	}
+
 	c.JSON(http.StatusOK, client)
 }
+
+type UserStats struct {
+	Username   string  `json:"username"`
+	Sessions   int64   `json:"sessions"`
+	TimeOnline float64 `json:"time_online"`
+	Distance   float64 `json:"distance"`
+	TopSpeed   float64 `json:"top_speed"`
+}
+
+func newUserStats(s database.UserStats) UserStats {
+	return UserStats{
+		Username:   s.UserName,
+		Sessions:   s.Sessions,
+		TimeOnline: s.TimeOnline,
+		Distance:   s.Distance,
+		TopSpeed:   s.TopSpeed,
+	}
+}
+
+var statsWindows = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// parseWindow turns the window query parameter into the start of the time
+// range to aggregate. An empty or "all" window covers every session.
+func parseWindow(c *gin.Context) (*time.Time, error) {
+	window := c.DefaultQuery("window", "all")
+	if window == "all" {
+		return nil, nil
+	}
+
+	d, ok := statsWindows[window]
+	if !ok {
+		return nil, fmt.Errorf("unknown window %q", window)
+	}
+	since := time.Now().Add(-d)
+	return &since, nil
+}
+
+func (s *Server) getUserStatsHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	since, err := parseWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := s.db.GetUser(username); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	stats, err := s.db.GetUserStats(username, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserStats(stats))
+}
+
+func (s *Server) getLeaderboardHandler(c *gin.Context) {
+	metric := c.Param("metric")
+
+	since, err := parseWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	records, err := s.db.GetLeaderboard(metric, since, limit)
+	if err == database.ErrUnknownMetric {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	leaderboard := []UserStats{}
+	for _, record := range records {
+		leaderboard = append(leaderboard, newUserStats(record))
+	}
+	c.JSON(http.StatusOK, gin.H{"metric": metric, "entries": leaderboard})
+}
+
+func (s *Server) getTrailSettingsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.manager.trailSettings(c.Param("room")))
+}
+
+func (s *Server) updateTrailSettingsHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	var req TrailSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.Seconds < 0 || *req.Seconds > maxTrailSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("seconds must be between 0 and %d", maxTrailSeconds)})
+		return
+	}
+
+	if err := s.manager.setTrailWindow(room, *req.Seconds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.manager.trailSettings(room))
+}
+
+func (s *Server) getStrokesHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	records, err := s.db.GetStrokes(room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	strokes := []Stroke{}
+	for _, record := range records {
+		strokes = append(strokes, newStroke(record))
+	}
+	c.JSON(http.StatusOK, strokes)
+}
+
+func (s *Server) getCommentsHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	records, err := s.db.GetComments(room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	comments := []Comment{}
+	for _, record := range records {
+		comments = append(comments, newComment(record))
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+func (s *Server) createCommentHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	author, err := s.manager.identify(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req NewCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := s.db.CreateComment(database.Comment{
+		ID:     uuid.New(),
+		Room:   room,
+		Author: author,
+		X:      req.X,
+		Y:      req.Y,
+		Text:   req.Text,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment := newComment(record)
+	s.manager.broadcastComment(EventCommentCreate, comment)
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (s *Server) updateCommentHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := s.db.GetComment(id)
+	if err != nil || record.Room != room {
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return
+	}
+
+	// overwrite the fields that were provided
+	if req.Text != nil {
+		username, err := s.manager.identify(c.Request)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if username != record.Author {
+			c.JSON(http.StatusForbidden, gin.H{"error": errNotCommentAuthor.Error()})
+			return
+		}
+		record.Text = *req.Text
+	}
+	if req.Resolved != nil {
+		record.Resolved = *req.Resolved
+	}
+
+	record, err = s.db.UpdateComment(record)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment := newComment(record)
+	s.manager.broadcastComment(EventCommentUpdate, comment)
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (s *Server) getReactionsHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	records, err := s.db.GetReactionCounts(room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	counts := make(map[string]int64)
+	for _, record := range records {
+		counts[record.Emoji] = record.Count
+	}
+	c.JSON(http.StatusOK, gin.H{"room": room, "counts": counts})
+}
+
+func (s *Server) getZonesHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	records, err := s.db.GetZones(room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	zones := []*Zone{}
+	for _, record := range records {
+		zones = append(zones, newZone(record))
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+func (s *Server) createZoneHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	var req NewZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := req.record(room)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err = s.db.CreateZone(record)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone := newZone(record)
+	s.manager.addZone(zone)
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+func (s *Server) deleteZoneHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := s.db.GetZone(id)
+	if err != nil || zone.Room != room {
+		c.JSON(http.StatusNotFound, gin.H{"error": "zone not found"})
+		return
+	}
+
+	if err := s.db.DeleteZone(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.manager.removeZone(zone.Room, id)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) getZoneStatsHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if zone, err := s.db.GetZone(id); err != nil || zone.Room != room {
+		c.JSON(http.StatusNotFound, gin.H{"error": "zone not found"})
+		return
+	}
+
+	stats, err := s.db.GetZoneStats(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ZoneStats{
+		ZoneID:     stats.ZoneID,
+		Visits:     stats.Visits,
+		TotalDwell: stats.TotalDwell,
+		MaxDwell:   stats.MaxDwell,
+	})
+}
+
+func (s *Server) getWebhooksHandler(c *gin.Context) {
+	records, err := s.db.GetWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhooks := []Webhook{}
+	for _, record := range records {
+		webhooks = append(webhooks, newWebhook(record))
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func (s *Server) createWebhookHandler(c *gin.Context) {
+	var req NewWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkWebhookURL(req.URL, s.manager.webhooks.allowPrivate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := s.db.CreateWebhook(database.Webhook{
+		ID:     uuid.New(),
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: secret,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.webhooks.reload(); err != nil {
+		s.manager.logger.Printf("error reloading webhooks: %v", err)
+	}
+
+	// the secret is only shown once, for the receiver to check signatures
+	webhook := newWebhook(record)
+	webhook.Secret = record.Secret
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (s *Server) deleteWebhookHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.db.DeleteWebhook(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.webhooks.reload(); err != nil {
+		s.manager.logger.Printf("error reloading webhooks: %v", err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// rotateWebhookSecretHandler replaces the webhook's secret, which also starts
+// deliveries to a webhook registered before secrets existed.
+func (s *Server) rotateWebhookSecretHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := s.db.GetWebhook(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+
+	record.Secret, err = newWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.db.UpdateWebhook(record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.webhooks.reload(); err != nil {
+		s.manager.logger.Printf("error reloading webhooks: %v", err)
+	}
+
+	webhook := newWebhook(record)
+	webhook.Secret = record.Secret
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (s *Server) getWebhookDeliveriesHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	records, err := s.db.GetWebhookDeliveries(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, record := range records {
+		deliveries = append(deliveries, newWebhookDelivery(record))
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (s *Server) getWebhookDeadLettersHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := s.db.GetWebhookDeadLetters(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	letters := []WebhookDeadLetter{}
+	for _, record := range records {
+		letters = append(letters, newWebhookDeadLetter(record))
+	}
+	c.JSON(http.StatusOK, letters)
+}
+
+func (s *Server) getPollsHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	records, err := s.db.GetPolls(room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	polls := []PollResult{}
+	for _, record := range records {
+		polls = append(polls, pollResults(record, s.manager.loadVotes(record.ID)))
+	}
+	c.JSON(http.StatusOK, polls)
+}
+
+func (s *Server) createPollHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	facilitator, err := s.manager.identify(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req NewPollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.validatePoll(room, req.Options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dwell := req.DwellMs
+	if dwell <= 0 {
+		dwell = defaultPollDwell.Milliseconds()
+	}
+
+	record, err := s.db.CreatePoll(database.Poll{
+		ID:          uuid.New(),
+		Room:        room,
+		Question:    req.Question,
+		Facilitator: facilitator,
+		DwellMs:     dwell,
+		Options:     req.Options,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.manager.addPoll(record)
+
+	result := pollResults(record, nil)
+	s.manager.broadcastPoll(result)
+
+	c.JSON(http.StatusCreated, result)
+}
+
+func (s *Server) getPollHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := s.db.GetPoll(id)
+	if err != nil || record.Room != room {
+		c.JSON(http.StatusNotFound, gin.H{"error": "poll not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pollResults(record, s.manager.loadVotes(record.ID)))
+}
+
+func (s *Server) updatePollHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req UpdatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := s.db.GetPoll(id)
+	if err != nil || record.Room != room {
+		c.JSON(http.StatusNotFound, gin.H{"error": "poll not found"})
+		return
+	}
+
+	// polls can only be closed, not reopened
+	if req.Closed != nil && *req.Closed && !record.Closed {
+		record.Closed = true
+		record, err = s.db.UpdatePoll(record)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		s.manager.closePoll(record)
+	}
+
+	c.JSON(http.StatusOK, pollResults(record, s.manager.loadVotes(record.ID)))
+}
+
+type BehaviourRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type GameScore struct {
+	Game     string `json:"game"`
+	Room     string `json:"room"`
+	Username string `json:"username"`
+	Score    int64  `json:"score"`
+}
+
+func (s *Server) getBehaviourHandler(c *gin.Context) {
+	state, ok := s.manager.behaviourState(c.Param("room"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": errNoBehaviour.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+func (s *Server) startBehaviourHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	var req BehaviourRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err := s.manager.startBehaviour(room, req.Name); err {
+	case nil:
+	case errUnknownBehaviour:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errBehaviourRunning:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	state, _ := s.manager.behaviourState(room)
+	c.JSON(http.StatusOK, state)
+}
+
+func (s *Server) stopBehaviourHandler(c *gin.Context) {
+	switch err := s.manager.stopBehaviour(c.Param("room")); err {
+	case nil:
+		c.Status(http.StatusNoContent)
+	case errNoBehaviour:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (s *Server) getGameScoresHandler(c *gin.Context) {
+	records, err := s.db.GetGameScores(c.Param("game"), c.Query("room"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scores := []GameScore{}
+	for _, record := range records {
+		scores = append(scores, GameScore{
+			Game:     record.Game,
+			Room:     record.Room,
+			Username: record.UserName,
+			Score:    record.Score,
+		})
+	}
+	c.JSON(http.StatusOK, scores)
+}
@@ -3,9 +3,13 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func TestHelloWorldHandler(t *testing.T) {
@@ -31,3 +35,105 @@ func TestHelloWorldHandler(t *testing.T) {
 		t.Errorf("Handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
 	}
 }
+
+func TestCreateCommentHandler(t *testing.T) {
+	m := newTestManager(t)
+	listener := addTestClient(m, 0, 0)
+	listener.username, listener.token = "alice", "alice-token"
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.POST("/rooms/:room/comments", s.createCommentHandler)
+
+	body := `{"author":"mallory","x":10,"y":20,"text":"looks good"}`
+	req, err := http.NewRequest("POST", "/rooms/lobby/comments", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+	}
+
+	req, err = http.NewRequest("POST", "/rooms/lobby/comments", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(SessionTokenHeader, listener.token)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	if len(m.db.(*fakeDB).comments) != 1 {
+		t.Errorf("expected the comment to be stored")
+	}
+	for _, comment := range m.db.(*fakeDB).comments {
+		if comment.Author != "alice" {
+			t.Errorf("expected the author to be the caller, got %s", comment.Author)
+		}
+	}
+	select {
+	case event := <-listener.egress:
+		if event.Type != EventCommentCreate {
+			t.Errorf("expected %s event, got %s", EventCommentCreate, event.Type)
+		}
+	default:
+		t.Errorf("expected the comment to be pushed to the room")
+	}
+
+	// text is required
+	req, err = http.NewRequest("POST", "/rooms/lobby/comments", strings.NewReader(`{"x":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(SessionTokenHeader, listener.token)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestUpdateCommentHandler(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	alice.username, alice.token = "alice", "alice-token"
+	bob := addTestClient(m, 10, 10)
+	bob.username, bob.token = "bob", "bob-token"
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.PATCH("/rooms/:room/comments/:id", s.updateCommentHandler)
+
+	comment, _ := m.db.CreateComment(database.Comment{ID: uuid.New(), Room: DefaultRoom, Author: "alice", Text: "looks good"})
+	update := func(token, body string) int {
+		req := httptest.NewRequest("PATCH", "/rooms/lobby/comments/"+comment.ID.String(), strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(SessionTokenHeader, token)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := update(bob.token, `{"text":"looks bad"}`); code != http.StatusForbidden {
+		t.Fatalf("expected bob not to edit alice's comment, got %d", code)
+	}
+	if code := update("", `{"text":"looks bad"}`); code != http.StatusUnauthorized {
+		t.Fatalf("expected an edit without a session to be refused, got %d", code)
+	}
+	if code := update(bob.token, `{"resolved":true}`); code != http.StatusOK {
+		t.Fatalf("expected bob to resolve alice's comment, got %d", code)
+	}
+	if code := update(alice.token, `{"text":"looks great"}`); code != http.StatusOK {
+		t.Fatalf("expected alice to edit their comment, got %d", code)
+	}
+
+	stored, _ := m.db.GetComment(comment.ID)
+	if stored.Text != "looks great" || !stored.Resolved {
+		t.Fatalf("expected the edit and the resolution to be stored, got %+v", stored)
+	}
+}
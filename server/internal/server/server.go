@@ -12,7 +12,7 @@ import (
 	"github.com/gorilla/websocket"
 	_ "github.com/joho/godotenv/autoload"
 
-	"server/internal/database"
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
 )
 
 type Server struct {
@@ -29,12 +29,14 @@ func NewServer() *http.Server {
 
 	db.ResetAllSessions()
 
+	manager := NewManager(&db, envOptions()...)
+
 	NewServer := &Server{
 		port:    port,
 		conns:   make(map[*websocket.Conn]bool),
 		hub:     make(map[uuid.UUID]Client),
 		db:      db,
-		manager: NewManager(&db),
+		manager: manager,
 	}
 
 	// Declare Server config
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const EventSession = "session"
+
+var (
+	errNoSession   = errors.New("a live session token is required")
+	errNotSameUser = errors.New("cannot act for another user")
+)
+
+// Session is the first event a client receives: its ID and the token that
+// identifies its user over REST. SSE clients also POST their events to /sse
+// with the token in the X-Session-Token header.
+type Session struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+// sessionToken returns the token a client identifies itself with: the SSE
+// fallback's, which it already posts events with, or a new one.
+func sessionToken(conn transport) string {
+	if t, ok := conn.(*sseTransport); ok {
+		return t.token
+	}
+	return uuid.NewString()
+}
+
+// identify returns the user behind a REST request, with the manager's
+// authenticator if it has one and otherwise from the session token of one of
+// the user's live connections.
+func (m *Manager) identify(r *http.Request) (string, error) {
+	if m.auth != nil {
+		return m.auth(r)
+	}
+
+	token := r.Header.Get(SessionTokenHeader)
+	if token == "" {
+		return "", errNoSession
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	for client := range m.Clients {
+		if client.token != "" && subtle.ConstantTimeCompare([]byte(client.token), []byte(token)) == 1 {
+			return client.username, nil
+		}
+	}
+	return "", errNoSession
+}
+
+// callerAuth only lets requests through from users the manager's
+// authenticator accepts. Without an authenticator, as in the standalone
+// server, anyone may call the route.
+func (m *Manager) callerAuth(c *gin.Context) {
+	if m.auth == nil {
+		c.Next()
+		return
+	}
+	if _, err := m.auth(c.Request); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.Next()
+}
+
+// userAuth only lets requests through from the user named by the username
+// parameter.
+func (m *Manager) userAuth(c *gin.Context) {
+	username, err := m.identify(c.Request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if username != c.Param("username") {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errNotSameUser.Error()})
+		return
+	}
+	c.Next()
+}
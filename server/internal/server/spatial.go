@@ -0,0 +1,240 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// maxCoordinate bounds the positions and viewports clients may report, which
+// keeps the grid's cell indices well within an int.
+const maxCoordinate = 1e9
+
+var errInvalidViewport = errors.New("viewport must be finite")
+
+// Viewport is the area of the canvas a client is currently looking at.
+type Viewport struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+func (v Viewport) grow(margin float64) Viewport {
+	return Viewport{
+		X: v.X - margin,
+		Y: v.Y - margin,
+		W: v.W + 2*margin,
+		H: v.H + 2*margin,
+	}
+}
+
+func (v Viewport) contains(x, y float64) bool {
+	return x >= v.X && x <= v.X+v.W && y >= v.Y && y <= v.Y+v.H
+}
+
+// valid reports whether every field of the viewport is a finite number.
+func (v Viewport) valid() bool {
+	return finite(v.X) && finite(v.Y) && finite(v.W) && finite(v.H)
+}
+
+// clamp limits the viewport to the coordinates clients can report.
+func (v Viewport) clamp() Viewport {
+	x0, y0 := clampCoordinate(v.X), clampCoordinate(v.Y)
+	x1, y1 := clampCoordinate(v.X+v.W), clampCoordinate(v.Y+v.H)
+	return Viewport{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
+
+func finite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+// validPosition reports whether (x, y) is a point clients may move to.
+func validPosition(x, y float64) bool {
+	return finite(x) && finite(y) && math.Abs(x) <= maxCoordinate && math.Abs(y) <= maxCoordinate
+}
+
+func clampCoordinate(f float64) float64 {
+	return math.Max(-maxCoordinate, math.Min(maxCoordinate, f))
+}
+
+func (v Viewport) center() Position {
+	return Position{X: v.X + v.W/2, Y: v.Y + v.H/2}
+}
+
+type cell struct {
+	x, y int
+}
+
+// spatialGrid is a uniform grid of client positions, used to look up the
+// clients that fall within a receiver's area of interest without scanning
+// every connected client.
+type spatialGrid struct {
+	size  float64
+	cells map[cell]ClientList
+	index map[*Client]cell
+}
+
+func newSpatialGrid(size float64) *spatialGrid {
+	if size <= 0 {
+		size = 256
+	}
+	return &spatialGrid{
+		size:  size,
+		cells: make(map[cell]ClientList),
+		index: make(map[*Client]cell),
+	}
+}
+
+func (g *spatialGrid) cellAt(x, y float64) cell {
+	return cell{
+		x: int(math.Floor(x / g.size)),
+		y: int(math.Floor(y / g.size)),
+	}
+}
+
+// move places the client in the cell for (x, y), inserting it if it is not
+// yet indexed.
+func (g *spatialGrid) move(c *Client, x, y float64) {
+	next := g.cellAt(x, y)
+	if prev, ok := g.index[c]; ok {
+		if prev == next {
+			return
+		}
+		g.removeFrom(c, prev)
+	}
+
+	clients, ok := g.cells[next]
+	if !ok {
+		clients = make(ClientList)
+		g.cells[next] = clients
+	}
+	clients[c] = true
+	g.index[c] = next
+}
+
+func (g *spatialGrid) remove(c *Client) {
+	if prev, ok := g.index[c]; ok {
+		g.removeFrom(c, prev)
+		delete(g.index, c)
+	}
+}
+
+func (g *spatialGrid) removeFrom(c *Client, k cell) {
+	clients := g.cells[k]
+	delete(clients, c)
+	if len(clients) == 0 {
+		delete(g.cells, k)
+	}
+}
+
+// query returns the clients whose current position lies within v.
+func (g *spatialGrid) query(v Viewport) []*Client {
+	if g == nil {
+		return nil
+	}
+
+	var found []*Client
+
+	// an area spanning more cells than are occupied is cheaper to answer by
+	// scanning the occupied ones, and its corners may not fit a cell index
+	cols := math.Floor((v.X+v.W)/g.size) - math.Floor(v.X/g.size) + 1
+	rows := math.Floor((v.Y+v.H)/g.size) - math.Floor(v.Y/g.size) + 1
+	if !(cols*rows <= float64(len(g.cells))) {
+		for _, clients := range g.cells {
+			for client := range clients {
+				if v.contains(client.state.X, client.state.Y) {
+					found = append(found, client)
+				}
+			}
+		}
+		return found
+	}
+
+	min := g.cellAt(v.X, v.Y)
+	max := g.cellAt(v.X+v.W, v.Y+v.H)
+	for x := min.x; x <= max.x; x++ {
+		for y := min.y; y <= max.y; y++ {
+			for client := range g.cells[cell{x, y}] {
+				if v.contains(client.state.X, client.state.Y) {
+					found = append(found, client)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// gridFor returns the spatial index for a room, creating it on first use.
+// Callers must hold the manager lock.
+func (m *Manager) gridFor(room string) *spatialGrid {
+	g, ok := m.grids[room]
+	if !ok {
+		g = newSpatialGrid(m.cellSize)
+		m.grids[room] = g
+	}
+	return g
+}
+
+// interested reports whether the point (x, y) is within c's area of interest.
+func (m *Manager) interested(c *Client, x, y float64) bool {
+	if c.viewport == nil {
+		return true
+	}
+	return c.viewport.grow(m.interestMargin).contains(x, y)
+}
+
+// visibleClients returns the clients and ghosts in c's room whose cursors
+// should be sent to c, leaving out users c has blocked. When c has reported a
+// viewport only clients within it (plus the interest margin) are included,
+// and the result is capped at maxCursors, keeping those closest to the
+// receiver. The receiver itself is always included unless it is a spectator.
+// Callers must hold at least the manager read lock.
+func (m *Manager) visibleClients(c *Client) []*Client {
+	var candidates []*Client
+	if c.viewport == nil {
+		for _, clients := range []ClientList{m.Clients, m.ghosts} {
+			for client := range clients {
+				if client.room == c.room {
+					candidates = append(candidates, client)
+				}
+			}
+		}
+	} else {
+		candidates = m.grids[c.room].query(c.viewport.grow(m.interestMargin))
+	}
+
+	// spectators have no cursor of their own
+	visible := []*Client{}
+	if !c.spectator {
+		visible = append(visible, c)
+	}
+	for _, client := range candidates {
+		if client != c && !c.blocks(client.username) {
+			visible = append(visible, client)
+		}
+	}
+
+	if m.maxCursors <= 0 || len(visible) <= m.maxCursors {
+		return visible
+	}
+
+	focus := Position{X: c.state.X, Y: c.state.Y}
+	if c.viewport != nil {
+		focus = c.viewport.center()
+	}
+
+	others := visible
+	if !c.spectator {
+		others = visible[1:]
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return distance(focus, others[i].state) < distance(focus, others[j].state)
+	})
+
+	return visible[:m.maxCursors]
+}
+
+func distance(p Position, s State) float64 {
+	return math.Hypot(s.X-p.X, s.Y-p.Y)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpatialGridQuery(t *testing.T) {
+	g := newSpatialGrid(100)
+	near := &Client{state: State{X: 10, Y: 10}}
+	far := &Client{state: State{X: 1000, Y: 1000}}
+	g.move(near, near.state.X, near.state.Y)
+	g.move(far, far.state.X, far.state.Y)
+
+	found := g.query(Viewport{X: -50, Y: -50, W: 100, H: 100})
+	if len(found) != 1 || found[0] != near {
+		t.Fatalf("expected only the near client, got %d clients", len(found))
+	}
+
+	far.state = State{X: 20, Y: 20}
+	g.move(far, far.state.X, far.state.Y)
+	if found := g.query(Viewport{X: -50, Y: -50, W: 100, H: 100}); len(found) != 2 {
+		t.Fatalf("expected both clients after move, got %d", len(found))
+	}
+
+	g.remove(near)
+	if found := g.query(Viewport{X: -50, Y: -50, W: 100, H: 100}); len(found) != 1 {
+		t.Fatalf("expected one client after remove, got %d", len(found))
+	}
+}
+
+func TestSpatialGridQueryHugeViewport(t *testing.T) {
+	g := newSpatialGrid(256)
+	inside := &Client{state: State{X: 10, Y: 10}}
+	g.move(inside, inside.state.X, inside.state.Y)
+
+	// walking every cell in range would never finish
+	found := g.query(Viewport{X: -1e18, Y: -1e18, W: 2e18, H: 2e18})
+	if len(found) != 1 || found[0] != inside {
+		t.Fatalf("expected the client to be found, got %d clients", len(found))
+	}
+}
+
+func TestUpdateViewportClamped(t *testing.T) {
+	m := newTestManager(t)
+	c := addTestClient(m, 0, 0)
+
+	event := Event{Type: EventUpdateViewport, Payload: json.RawMessage(`{"x":-1e300,"y":-1e300,"w":2e300,"h":2e300}`)}
+	if err := UpdateViewport(event, c); err != nil {
+		t.Fatal(err)
+	}
+	if c.viewport.X != -maxCoordinate || c.viewport.W != 2*maxCoordinate {
+		t.Errorf("expected the viewport to be clamped, got %+v", *c.viewport)
+	}
+}
+
+func TestVisibleClientsViewport(t *testing.T) {
+	m := newTestManager(t)
+	receiver := addTestClient(m, 0, 0)
+	inside := addTestClient(m, 50, 50)
+	addTestClient(m, 500, 500)
+
+	receiver.viewport = &Viewport{X: -100, Y: -100, W: 200, H: 200}
+
+	visible := m.visibleClients(receiver)
+	if len(visible) != 2 {
+		t.Fatalf("expected 2 visible clients, got %d", len(visible))
+	}
+	if visible[0] != receiver || visible[1] != inside {
+		t.Fatalf("unexpected visible clients: %v", visible)
+	}
+}
+
+func TestVisibleClientsCap(t *testing.T) {
+	m := newTestManager(t)
+	m.maxCursors = 2
+	receiver := addTestClient(m, 0, 0)
+	closest := addTestClient(m, 10, 0)
+	addTestClient(m, 20, 0)
+	addTestClient(m, 30, 0)
+
+	visible := m.visibleClients(receiver)
+	if len(visible) != 2 {
+		t.Fatalf("expected cap of 2 clients, got %d", len(visible))
+	}
+	if visible[0] != receiver || visible[1] != closest {
+		t.Fatalf("expected receiver and closest client, got %v", visible)
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModeSpectate is the /ws mode for read-only connections.
+const ModeSpectate = "spectate"
+
+var errSpectatorReadOnly = errors.New("spectators cannot send this event")
+
+// spectatorEvents are the events a spectator may send. They only affect what
+// the spectator itself receives.
+var spectatorEvents = map[string]bool{
+	EventUpdateViewport: true,
+	EventFollow:         true,
+	EventUnfollow:       true,
+}
+
+// NewSpectator creates a read-only client for dashboards and recorders. It
+// receives the room's broadcasts but has no user, session or cursor.
+func NewSpectator(room string, conn transport, manager *Manager) *Client {
+	return &Client{
+		id:        uuid.New(),
+		room:      room,
+		spectator: true,
+		conn:      conn,
+		manager:   manager,
+
+		connectedAt: time.Now(),
+
+		egress: make(chan Event, egressBuffer),
+	}
+}
+
+func (m *Manager) addSpectator(client *Client) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.spectators[client] = true
+}
+
+func (m *Manager) removeSpectator(client *Client) {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.spectators[client]; ok {
+		m.unfollow(client)
+		client.conn.Close()
+		client.closeEgress()
+		delete(m.spectators, client)
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpectator(t *testing.T) {
+	m := newTestManager(t)
+	player := addTestClient(m, 0, 0)
+
+	spectator := NewSpectator(DefaultRoom, nil, m)
+	spectator.egress = make(chan Event, 64)
+	m.addSpectator(spectator)
+
+	if err := m.routeEvent(Event{Type: EventUpdatePosition, Payload: json.RawMessage(`{"x":1,"y":1}`)}, spectator); err != errSpectatorReadOnly {
+		t.Fatalf("expected spectator updates to be rejected, got %v", err)
+	}
+
+	m.broadcastRoomState(DefaultRoom)
+
+	var cursors map[string]json.RawMessage
+	if err := json.Unmarshal(receive(t, spectator, "broadcast").Payload, &cursors); err != nil {
+		t.Fatal(err)
+	}
+	if len(cursors) != 1 {
+		t.Fatalf("expected the spectator to see only the player, got %d cursors", len(cursors))
+	}
+	if _, ok := cursors[player.id.String()]; !ok {
+		t.Fatal("expected the player's cursor in the broadcast")
+	}
+
+	var playerCursors map[string]json.RawMessage
+	if err := json.Unmarshal(receive(t, player, "broadcast").Payload, &playerCursors); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := playerCursors[spectator.id.String()]; ok {
+		t.Fatal("expected the spectator not to appear to players")
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// SessionTokenHeader carries a client's session token, tying an HTTP POST
+	// to its SSE connection and REST requests to the client's user.
+	SessionTokenHeader = "X-Session-Token"
+
+	sseKeepAlive = 15 * time.Second
+
+	// maxPostedEvent bounds the size of an event sent over HTTP POST
+	maxPostedEvent = 64 << 10
+)
+
+var errTransportClosed = errors.New("transport closed")
+
+// sseTransport is the fallback for clients that can't open a WebSocket. The
+// client receives its events as Server-Sent Events and sends its own over
+// HTTP POST; the transport joins the two into the same message stream a
+// WebSocket would give the client's read and write loops.
+type sseTransport struct {
+	token    string
+	inbound  chan []byte
+	outbound chan []byte
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	reason    string
+}
+
+func newSSETransport() *sseTransport {
+	return &sseTransport{
+		token:    uuid.NewString(),
+		inbound:  make(chan []byte, egressBuffer),
+		outbound: make(chan []byte, egressBuffer),
+		closed:   make(chan struct{}),
+	}
+}
+
+// ReadMessage blocks until the client POSTs an event or the stream closes.
+func (t *sseTransport) ReadMessage() (int, []byte, error) {
+	select {
+	case payload := <-t.inbound:
+		return websocket.TextMessage, payload, nil
+	case <-t.closed:
+		return 0, nil, errTransportClosed
+	}
+}
+
+func (t *sseTransport) WriteMessage(messageType int, data []byte) error {
+	if messageType == websocket.CloseMessage {
+		return t.Close()
+	}
+
+	select {
+	case t.outbound <- data:
+		return nil
+	case <-t.closed:
+		return errTransportClosed
+	}
+}
+
+// WriteControl only handles close messages, whose reason is passed on to the
+// client before the stream ends.
+func (t *sseTransport) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != websocket.CloseMessage {
+		return nil
+	}
+	t.closeWith(closeReason(data))
+	return nil
+}
+
+func (t *sseTransport) Close() error {
+	t.closeWith("")
+	return nil
+}
+
+func (t *sseTransport) closeWith(reason string) {
+	t.closeOnce.Do(func() {
+		t.reason = reason
+		close(t.closed)
+	})
+}
+
+// closeReason extracts the text from a websocket close frame payload.
+func closeReason(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	return string(data[2:])
+}
+
+// post hands an event POSTed by the client to its read loop.
+func (t *sseTransport) post(payload []byte) error {
+	select {
+	case t.inbound <- payload:
+		return nil
+	case <-t.closed:
+		return errTransportClosed
+	default:
+		return errRateLimited
+	}
+}
+
+// initiateSSEConnection connects a client over the SSE fallback. It takes the
+// same query parameters as /ws.
+func (m *Manager) initiateSSEConnection(c *gin.Context) {
+	username, ok := m.admit(c)
+	if !ok {
+		return
+	}
+
+	t := newSSETransport()
+	m.Lock()
+	m.fallbacks[t.token] = t
+	m.Unlock()
+
+	defer func() {
+		m.Lock()
+		delete(m.fallbacks, t.token)
+		m.Unlock()
+	}()
+
+	client := m.connect(c, t, username)
+	m.streamSSE(c, t, Session{ID: client.id.String(), Token: t.token})
+}
+
+// streamSSE writes the session and then every outgoing message as SSE until
+// the transport is closed or the client goes away.
+func (m *Manager) streamSSE(c *gin.Context, t *sseTransport, session Session) {
+	// like the presence stream, this outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		m.logger.Printf("failed to clear the SSE deadline: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.SSEvent(EventSession, session)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-t.outbound:
+			// the data is the same JSON event a WebSocket client receives
+			c.SSEvent("message", json.RawMessage(data))
+		case <-keepAlive.C:
+			io.WriteString(c.Writer, ": keep-alive\n\n")
+		case <-t.closed:
+			if t.reason != "" {
+				c.SSEvent("close", gin.H{"reason": t.reason})
+				c.Writer.Flush()
+			}
+			return
+		case <-c.Request.Context().Done():
+			t.Close()
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// postSSEEventHandler accepts an event from an SSE client and routes it like
+// a message read from a WebSocket.
+func (m *Manager) postSSEEventHandler(c *gin.Context) {
+	m.RLock()
+	t, ok := m.fallbacks[c.GetHeader(SessionTokenHeader)]
+	m.RUnlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPostedEvent+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(payload) > maxPostedEvent {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "event too large"})
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err := t.post(payload); err {
+	case nil:
+		c.Status(http.StatusAccepted)
+	case errRateLimited:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestPostSSEEventHandler(t *testing.T) {
+	m := newTestManager(t)
+	transport := newSSETransport()
+	m.fallbacks[transport.token] = transport
+	r := gin.New()
+	r.POST("/sse", m.postSSEEventHandler)
+
+	body := `{"type":"update_position","payload":{"x":1,"y":2}}`
+	req := httptest.NewRequest("POST", "/sse", strings.NewReader(body))
+	req.Header.Set(SessionTokenHeader, transport.token)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("got status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	_, payload, err := transport.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != body {
+		t.Fatalf("expected the posted event to be read, got %s", payload)
+	}
+
+	req = httptest.NewRequest("POST", "/sse", strings.NewReader(body))
+	req.Header.Set(SessionTokenHeader, "unknown")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown session to be refused, got %d", rr.Code)
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	m := newTestManager(t)
+	transport := newSSETransport()
+	r := gin.New()
+	r.GET("/sse", func(c *gin.Context) {
+		m.streamSSE(c, transport, Session{ID: "client", Token: transport.token})
+	})
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() (string, string) {
+		t.Helper()
+		var name string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimSpace(line)
+			if event, ok := strings.CutPrefix(line, "event:"); ok {
+				name = event
+			}
+			if data, ok := strings.CutPrefix(line, "data:"); ok {
+				return name, data
+			}
+		}
+	}
+
+	if name, data := next(); name != "session" || !strings.Contains(data, transport.token) {
+		t.Fatalf("expected the session first, got %s %s", name, data)
+	}
+
+	// the stream outlives the server's write timeout
+	time.Sleep(100 * time.Millisecond)
+
+	event, _ := NewEvent(EventUpdatePosition, State{X: 3})
+	data, _ := json.Marshal(event)
+	if err := transport.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatal(err)
+	}
+	if name, got := next(); name != "message" || got != string(data) {
+		t.Fatalf("expected the event as a message, got %s %s", name, got)
+	}
+
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "kicked")
+	transport.WriteControl(websocket.CloseMessage, msg, time.Now())
+	if name, got := next(); name != "close" || !strings.Contains(got, "kicked") {
+		t.Fatalf("expected the close reason, got %s %s", name, got)
+	}
+	if _, _, err := transport.ReadMessage(); err != errTransportClosed {
+		t.Fatalf("expected reads to fail once closed, got %v", err)
+	}
+}
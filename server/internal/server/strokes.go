@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventStrokeBegin = "stroke_begin"
+	EventStrokePoint = "stroke_point"
+	EventStrokeEnd   = "stroke_end"
+	EventStrokeErase = "stroke_erase"
+	EventStrokeAck   = "stroke_ack"
+
+	// maxStrokePoints bounds the size of a single stroke
+	maxStrokePoints = 10000
+
+	// maxOpenStrokes bounds the strokes a client can draw at once
+	maxOpenStrokes = 8
+
+	// maxStrokeRef bounds the client's reference for a new stroke, in bytes
+	maxStrokeRef = 64
+)
+
+var (
+	errStrokeNotFound  = errors.New("stroke not found")
+	errStrokeTooLong   = errors.New("stroke has too many points")
+	errStrokeNotAuthor = errors.New("only the author can erase a stroke")
+	errTooManyStrokes  = errors.New("too many strokes in progress")
+	errStrokeRef       = errors.New("stroke reference is too long")
+)
+
+type Stroke struct {
+	ID        uuid.UUID              `json:"id"`
+	Ref       string                 `json:"ref,omitempty"`
+	Room      string                 `json:"room"`
+	Author    string                 `json:"author"`
+	Color     int                    `json:"color"`
+	Width     float64                `json:"width"`
+	Points    []database.StrokePoint `json:"points"`
+	CreatedAt *time.Time             `json:"created_at,omitempty"`
+}
+
+func newStroke(s database.Stroke) Stroke {
+	return Stroke{
+		ID:        s.ID,
+		Room:      s.Room,
+		Author:    s.Author,
+		Color:     s.Color,
+		Width:     s.Width,
+		Points:    s.Points,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+type StrokeEvent struct {
+	ID     uuid.UUID              `json:"id"`
+	Ref    string                 `json:"ref"`
+	Color  *int                   `json:"color"`
+	Width  float64                `json:"width"`
+	Points []database.StrokePoint `json:"points"`
+}
+
+// StrokeAck tells the author which ID the server gave the stroke it began
+// with ref.
+type StrokeAck struct {
+	ID  uuid.UUID `json:"id"`
+	Ref string    `json:"ref"`
+}
+
+// broadcastStroke relays a stroke event to everyone in the room. Point events
+// only carry the new points so clients can append them as they arrive.
+func broadcastStroke(c *Client, eventType string, stroke Stroke) error {
+	payload, err := json.Marshal(stroke)
+	if err != nil {
+		return err
+	}
+	c.manager.broadcast(c.room, Event{Type: eventType, Payload: payload}, nil)
+	return nil
+}
+
+// BeginStroke starts a new stroke owned by the client. The server picks the
+// stroke ID, so one client can't collide with or take over another's stroke.
+// The author learns it from a stroke_ack carrying the ref it began the stroke
+// with, which is also echoed in the stroke_begin broadcast, so it can match
+// IDs to strokes when several are open.
+func BeginStroke(event Event, c *Client) error {
+	var begin StrokeEvent
+	err := json.Unmarshal(event.Payload, &begin)
+	if err != nil {
+		return err
+	}
+
+	if len(c.strokes) >= maxOpenStrokes {
+		return errTooManyStrokes
+	}
+	if len(begin.Ref) > maxStrokeRef {
+		return errStrokeRef
+	}
+
+	color := c.color
+	if begin.Color != nil {
+		color = *begin.Color
+	}
+	width := begin.Width
+	if width <= 0 {
+		width = 2
+	}
+	if len(begin.Points) > maxStrokePoints {
+		return errStrokeTooLong
+	}
+
+	stroke := &database.Stroke{
+		ID:     uuid.New(),
+		Room:   c.room,
+		Author: c.username,
+		Color:  color,
+		Width:  width,
+		Points: begin.Points,
+	}
+	c.strokes[stroke.ID] = stroke
+
+	ack, err := NewEvent(EventStrokeAck, StrokeAck{ID: stroke.ID, Ref: begin.Ref})
+	if err != nil {
+		return err
+	}
+	c.enqueue(ack)
+
+	started := newStroke(*stroke)
+	started.Ref = begin.Ref
+	return broadcastStroke(c, EventStrokeBegin, started)
+}
+
+func AddStrokePoints(event Event, c *Client) error {
+	var add StrokeEvent
+	err := json.Unmarshal(event.Payload, &add)
+	if err != nil {
+		return err
+	}
+
+	stroke, ok := c.strokes[add.ID]
+	if !ok {
+		return errStrokeNotFound
+	}
+	if len(stroke.Points)+len(add.Points) > maxStrokePoints {
+		return errStrokeTooLong
+	}
+	stroke.Points = append(stroke.Points, add.Points...)
+
+	update := newStroke(*stroke)
+	update.Points = add.Points
+	return broadcastStroke(c, EventStrokePoint, update)
+}
+
+// EndStroke finishes the stroke and persists it for late joiners.
+func EndStroke(event Event, c *Client) error {
+	var end StrokeEvent
+	err := json.Unmarshal(event.Payload, &end)
+	if err != nil {
+		return err
+	}
+
+	stroke, ok := c.strokes[end.ID]
+	if !ok {
+		return errStrokeNotFound
+	}
+
+	return c.endStroke(stroke)
+}
+
+func (c *Client) endStroke(stroke *database.Stroke) error {
+	delete(c.strokes, stroke.ID)
+
+	if err := c.manager.db.CreateStroke(*stroke); err != nil {
+		return err
+	}
+
+	update := newStroke(*stroke)
+	update.Points = nil
+	return broadcastStroke(c, EventStrokeEnd, update)
+}
+
+// finishStrokes ends any strokes still in progress when the client goes away,
+// so the points others have already seen are kept.
+func (c *Client) finishStrokes() {
+	for _, stroke := range c.strokes {
+		if err := c.endStroke(stroke); err != nil {
+			c.manager.logger.Printf("error finishing stroke %s: %v", stroke.ID, err)
+		}
+	}
+}
+
+// EraseStroke deletes one of the client's own strokes.
+func EraseStroke(event Event, c *Client) error {
+	var erase StrokeEvent
+	err := json.Unmarshal(event.Payload, &erase)
+	if err != nil {
+		return err
+	}
+
+	stroke, err := c.manager.db.GetStroke(erase.ID)
+	if err != nil || stroke.Room != c.room {
+		return errStrokeNotFound
+	}
+	if stroke.Author != c.username {
+		return errStrokeNotAuthor
+	}
+
+	if err := c.manager.db.DeleteStroke(stroke.ID); err != nil {
+		return err
+	}
+
+	return broadcastStroke(c, EventStrokeErase, Stroke{ID: stroke.ID, Room: stroke.Room, Author: stroke.Author})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStrokeLifecycle(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+	alice.username = "alice"
+	bob := addTestClient(m, 0, 0)
+	bob.username = "bob"
+
+	// a client-chosen ID is ignored
+	taken := uuid.New()
+	begin := Event{Type: EventStrokeBegin, Payload: json.RawMessage(fmt.Sprintf(`{"id":%q,"ref":"pen-1","points":[{"x":0,"y":0}]}`, taken))}
+	if err := BeginStroke(begin, alice); err != nil {
+		t.Fatal(err)
+	}
+	var ack StrokeAck
+	if err := json.Unmarshal(receive(t, alice, EventStrokeAck).Payload, &ack); err != nil {
+		t.Fatal(err)
+	}
+	id := ack.ID
+	if id == taken || id == uuid.Nil || ack.Ref != "pen-1" {
+		t.Fatalf("expected the server to pick the stroke ID for pen-1, got %+v", ack)
+	}
+	var started Stroke
+	if err := json.Unmarshal(receive(t, alice, EventStrokeBegin).Payload, &started); err != nil {
+		t.Fatal(err)
+	}
+	if started.ID != id || started.Ref != "pen-1" {
+		t.Fatalf("expected the broadcast to echo the ref, got %+v", started)
+	}
+
+	events := []Event{
+		{Type: EventStrokePoint, Payload: json.RawMessage(fmt.Sprintf(`{"id":%q,"points":[{"x":1,"y":1},{"x":2,"y":2}]}`, id))},
+		{Type: EventStrokeEnd, Payload: json.RawMessage(fmt.Sprintf(`{"id":%q}`, id))},
+	}
+	handlers := []EventHandler{AddStrokePoints, EndStroke}
+	for i, event := range events {
+		if err := handlers[i](event, alice); err != nil {
+			t.Fatalf("%s: %v", event.Type, err)
+		}
+	}
+
+	saved, ok := m.db.(*fakeDB).strokes[id]
+	if !ok {
+		t.Fatal("expected stroke to be persisted")
+	}
+	if len(saved.Points) != 3 || saved.Author != "alice" {
+		t.Fatalf("unexpected stroke: %+v", saved)
+	}
+
+	erase := Event{Type: EventStrokeErase, Payload: json.RawMessage(fmt.Sprintf(`{"id":%q}`, id))}
+	if err := EraseStroke(erase, bob); err != errStrokeNotAuthor {
+		t.Fatalf("expected erase by another user to be rejected, got %v", err)
+	}
+	if err := EraseStroke(erase, alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.db.(*fakeDB).strokes[id]; ok {
+		t.Fatal("expected stroke to be erased")
+	}
+}
+
+func TestStrokeOpenLimit(t *testing.T) {
+	m := newTestManager(t)
+	alice := addTestClient(m, 0, 0)
+
+	begin := Event{Type: EventStrokeBegin, Payload: json.RawMessage(`{}`)}
+	for i := 0; i < maxOpenStrokes; i++ {
+		if err := BeginStroke(begin, alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := BeginStroke(begin, alice); err != errTooManyStrokes {
+		t.Fatalf("expected errTooManyStrokes, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	BehaviourTag = "tag"
+
+	// tagBackCooldown stops the new "it" from immediately tagging back the
+	// player who tagged them
+	tagBackCooldown = time.Second
+)
+
+type TagState struct {
+	Game       string         `json:"game"`
+	It         string         `json:"it,omitempty"`
+	ItUsername string         `json:"it_username,omitempty"`
+	Radius     float64        `json:"radius"`
+	Scores     map[string]int `json:"scores"`
+}
+
+// tagGame is a game of tag played with cursors. One player is "it"; when
+// their cursor touches another player's, that player becomes "it" and the
+// tagger scores a point.
+type tagGame struct {
+	radius float64
+	now    func() time.Time
+
+	players ClientList
+	it      *Client
+
+	// the player who last tagged "it", and until when they cannot be tagged
+	// back
+	tagger      *Client
+	immuneUntil time.Time
+
+	scores  map[string]int
+	pending map[string]int64
+}
+
+func newTagGame(radius float64) *tagGame {
+	return &tagGame{
+		radius:  radius,
+		now:     time.Now,
+		players: make(ClientList),
+		scores:  make(map[string]int),
+		pending: make(map[string]int64),
+	}
+}
+
+func (g *tagGame) Name() string {
+	return BehaviourTag
+}
+
+func (g *tagGame) Join(c *Client) bool {
+	g.players[c] = true
+	if g.it == nil {
+		g.it = c
+	}
+	return true
+}
+
+func (g *tagGame) Leave(c *Client) bool {
+	if _, ok := g.players[c]; !ok {
+		return false
+	}
+	delete(g.players, c)
+	if g.tagger == c {
+		g.tagger = nil
+	}
+	if g.it == c {
+		g.it = nil
+		for player := range g.players {
+			g.it = player
+			break
+		}
+	}
+	return true
+}
+
+// collides reports whether two cursors, each a circle of the game's radius,
+// overlap.
+func (g *tagGame) collides(a, b *Client) bool {
+	return math.Hypot(a.state.X-b.state.X, a.state.Y-b.state.Y) <= 2*g.radius
+}
+
+func (g *tagGame) Move(c *Client, peers []*Client) bool {
+	if g.it == nil {
+		return false
+	}
+
+	var target *Client
+	if c == g.it {
+		for _, peer := range peers {
+			if g.players[peer] && g.collides(c, peer) && g.canTag(peer) {
+				target = peer
+				break
+			}
+		}
+	} else if g.players[c] && g.collides(c, g.it) && g.canTag(c) {
+		target = c
+	}
+
+	if target == nil {
+		return false
+	}
+
+	tagger := g.it
+	g.it = target
+	g.tagger = tagger
+	g.immuneUntil = g.now().Add(tagBackCooldown)
+	g.scores[tagger.username]++
+	g.pending[tagger.username]++
+	return true
+}
+
+func (g *tagGame) canTag(target *Client) bool {
+	return target != g.tagger || g.now().After(g.immuneUntil)
+}
+
+func (g *tagGame) State() interface{} {
+	state := TagState{
+		Game:   BehaviourTag,
+		Radius: g.radius,
+		Scores: make(map[string]int),
+	}
+	if g.it != nil {
+		state.It = g.it.id.String()
+		state.ItUsername = g.it.username
+	}
+	for username, score := range g.scores {
+		state.Scores[username] = score
+	}
+	return state
+}
+
+func (g *tagGame) Scores() map[string]int64 {
+	scores := g.pending
+	g.pending = make(map[string]int64)
+	return scores
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTagGame(t *testing.T) {
+	m := newTestManager(t)
+	m.behaviourFactories[BehaviourTag] = func() RoomBehaviour { return newTagGame(10) }
+
+	alice := addTestClient(m, 0, 0)
+	alice.username = "alice"
+	bob := addTestClient(m, 100, 100)
+	bob.username = "bob"
+
+	if err := m.startBehaviour(DefaultRoom, BehaviourTag); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.startBehaviour(DefaultRoom, BehaviourTag); err != errBehaviourRunning {
+		t.Fatalf("expected a second start to fail, got %v", err)
+	}
+
+	game := m.behaviours[DefaultRoom].(*tagGame)
+	it, other := game.it, alice
+	if it == alice {
+		other = bob
+	}
+
+	// move "it" onto the other player
+	payload := json.RawMessage(`{"x":` + jsonFloat(other.state.X+5) + `,"y":` + jsonFloat(other.state.Y) + `,"delta":100}`)
+	if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: payload}, it); err != nil {
+		t.Fatal(err)
+	}
+
+	if game.it != other {
+		t.Fatal("expected the touched player to become it")
+	}
+	if m.db.(*fakeDB).scores[BehaviourTag+"/"+it.username] != 1 {
+		t.Fatal("expected the tagger's score to be persisted")
+	}
+
+	// the new "it" cannot tag straight back
+	if game.Move(other, []*Client{it}) {
+		t.Fatal("expected tag-back to be blocked during the cooldown")
+	}
+	game.now = func() time.Time { return time.Now().Add(2 * tagBackCooldown) }
+	if !game.Move(other, []*Client{it}) || game.it != it {
+		t.Fatal("expected tag-back after the cooldown")
+	}
+
+	// when "it" leaves another player takes over
+	game.Leave(it)
+	if game.it != other {
+		t.Fatal("expected the remaining player to become it")
+	}
+}
+
+func jsonFloat(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
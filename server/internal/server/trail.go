@@ -0,0 +1,148 @@
+package server
+
+import (
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+)
+
+const (
+	EventTrailSync = "trail_sync"
+
+	// maxTrailSeconds bounds the trail window a room can ask for
+	maxTrailSeconds = 60
+)
+
+// TrailPoint is a past cursor position. T is in Unix milliseconds.
+type TrailPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	T int64   `json:"t"`
+}
+
+// trailBuffer holds a client's recent positions, oldest first. Points are
+// pruned by age, so a room with a long window keeps its whole trail, with a
+// cap on the count so a client flooding updates can't grow it without bound.
+type trailBuffer struct {
+	points []TrailPoint
+	start  int
+}
+
+func newTrailBuffer() *trailBuffer {
+	return &trailBuffer{}
+}
+
+// push records a point and drops those older than maxAge before it, keeping at
+// most limit points. A limit of zero or less keeps every point in the window.
+func (b *trailBuffer) push(p TrailPoint, maxAge time.Duration, limit int) {
+	b.points = append(b.points, p)
+
+	oldest := p.T - maxAge.Milliseconds()
+	for b.start < len(b.points) && b.points[b.start].T < oldest {
+		b.start++
+	}
+	if limit > 0 && len(b.points)-b.start > limit {
+		b.start = len(b.points) - limit
+	}
+
+	// compact once the dropped points make up half the slice
+	if b.start > 0 && b.start >= len(b.points)/2 {
+		n := copy(b.points, b.points[b.start:])
+		b.points = b.points[:n]
+		b.start = 0
+	}
+}
+
+// since returns the points recorded at or after t, oldest first.
+func (b *trailBuffer) since(t int64) []TrailPoint {
+	points := []TrailPoint{}
+	for _, p := range b.points[b.start:] {
+		if p.T >= t {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+type TrailSettings struct {
+	Room    string `json:"room"`
+	Seconds int    `json:"seconds"`
+}
+
+type TrailSettingsRequest struct {
+	Seconds *int `json:"seconds" binding:"required"`
+}
+
+// trailWindow returns how much trail history a room keeps for late joiners,
+// loading its setting the first time the room is used. Callers must hold the
+// manager lock.
+func (m *Manager) trailWindow(room string) time.Duration {
+	window, ok := m.trailWindows[room]
+	if ok {
+		return window
+	}
+
+	window = m.defaultTrail
+	if record, err := m.db.GetRoom(room); err == nil {
+		window = time.Duration(record.TrailSeconds) * time.Second
+	}
+	m.trailWindows[room] = window
+	return window
+}
+
+// recordTrail adds a point to the client's trail, keeping as much as its room's
+// window covers. Callers must hold the manager lock.
+func (m *Manager) recordTrail(c *Client, p TrailPoint) {
+	window := m.trailWindow(c.room)
+	c.trail.push(p, window, int(window.Seconds()*float64(m.trailRate)))
+}
+
+func (m *Manager) trailSettings(room string) TrailSettings {
+	m.Lock()
+	defer m.Unlock()
+
+	return TrailSettings{Room: room, Seconds: int(m.trailWindow(room).Seconds())}
+}
+
+func (m *Manager) setTrailWindow(room string, seconds int) error {
+	if err := m.db.SaveRoom(database.Room{Name: room, TrailSeconds: seconds}); err != nil {
+		return err
+	}
+
+	m.Lock()
+	m.trailWindows[room] = time.Duration(seconds) * time.Second
+	m.Unlock()
+	return nil
+}
+
+// syncTrails sends a newly connected client the recent trail of every cursor
+// in its room, keyed by client ID, so it sees where others have just been.
+func (m *Manager) syncTrails(c *Client) error {
+	m.Lock()
+	window := m.trailWindow(c.room)
+	if window <= 0 {
+		m.Unlock()
+		return nil
+	}
+
+	since := time.Now().Add(-window).UnixMilli()
+	trails := make(map[string][]TrailPoint)
+	for _, clients := range []ClientList{m.Clients, m.ghosts} {
+		for client := range clients {
+			if client == c || client.room != c.room || client.trail == nil {
+				continue
+			}
+			if points := client.trail.since(since); len(points) > 0 {
+				trails[client.id.String()] = points
+			}
+		}
+	}
+	m.Unlock()
+
+	event, err := NewEvent(EventTrailSync, trails)
+	if err != nil {
+		return err
+	}
+	c.enqueue(event)
+	return nil
+}
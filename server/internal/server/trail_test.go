@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTrailBuffer(t *testing.T) {
+	b := newTrailBuffer()
+	for i := int64(1); i <= 5; i++ {
+		b.push(TrailPoint{X: float64(i), T: i * 1000}, 2*time.Second, 0)
+	}
+
+	points := b.since(0)
+	if len(points) != 3 || points[0].T != 3000 || points[2].T != 5000 {
+		t.Fatalf("expected the points within 2s of the latest, oldest first, got %+v", points)
+	}
+	if points := b.since(5000); len(points) != 1 {
+		t.Fatalf("expected 1 point at or after t=5000, got %d", len(points))
+	}
+}
+
+func TestTrailBufferLongWindow(t *testing.T) {
+	b := newTrailBuffer()
+	// a minute of updates at 60 per second
+	for i := int64(0); i < 3600; i++ {
+		b.push(TrailPoint{T: i * 1000 / 60}, time.Minute, 120*60)
+	}
+	if points := b.since(0); len(points) != 3600 {
+		t.Fatalf("expected the whole minute to be kept, got %d points", len(points))
+	}
+
+	// the cap still bounds a client sending faster than the rate
+	for i := int64(0); i < 100; i++ {
+		b.push(TrailPoint{T: 60000}, time.Minute, 50)
+	}
+	if points := b.since(0); len(points) != 50 {
+		t.Fatalf("expected the cap to keep 50 points, got %d", len(points))
+	}
+}
+
+func TestSyncTrails(t *testing.T) {
+	m := newTestManager(t)
+	mover := addTestClient(m, 0, 0)
+	now := time.Now()
+	mover.trail.push(TrailPoint{X: 1, T: now.Add(-10 * time.Second).UnixMilli()}, time.Minute, 0)
+	mover.trail.push(TrailPoint{X: 2, T: now.Add(-time.Second).UnixMilli()}, time.Minute, 0)
+
+	if err := m.setTrailWindow(DefaultRoom, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	joiner := addTestClient(m, 0, 0)
+	if err := m.syncTrails(joiner); err != nil {
+		t.Fatal(err)
+	}
+
+	var trails map[string][]TrailPoint
+	if err := json.Unmarshal(receive(t, joiner, EventTrailSync).Payload, &trails); err != nil {
+		t.Fatal(err)
+	}
+	points := trails[mover.id.String()]
+	if len(points) != 1 || points[0].X != 2 {
+		t.Fatalf("expected only the point within the room's window, got %+v", points)
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	EventUsers      = "users"
+	EventUserUpdate = "user_update"
+)
+
+// feedUser is a user as tracked by the active users feed.
+type feedUser struct {
+	User
+	lastSeen time.Time
+}
+
+// feedSubscriber is a /ws/users connection. It only receives events.
+type feedSubscriber struct {
+	conn   *websocket.Conn
+	egress chan Event
+	done   chan struct{}
+}
+
+// userFeed keeps the list of users and whether they are connected in memory,
+// so subscribers can be told about changes as they happen instead of polling
+// the database. The list is loaded once, when the manager starts.
+type userFeed struct {
+	db     database.Service
+	logger *log.Logger
+
+	mu          sync.Mutex
+	users       map[string]*feedUser
+	connections map[string]int
+	subscribers map[*feedSubscriber]bool
+}
+
+func newUserFeed(db database.Service, logger *log.Logger) *userFeed {
+	return &userFeed{
+		db:          db,
+		logger:      logger,
+		users:       make(map[string]*feedUser),
+		connections: make(map[string]int),
+		subscribers: make(map[*feedSubscriber]bool),
+	}
+}
+
+// load reads the users and their latest sessions from the database. Users
+// the feed already knows about are kept as they are.
+func (f *userFeed) load() {
+	records, err := f.db.GetUserSessions()
+	if err != nil {
+		f.logger.Printf("error loading users: %v", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, record := range records {
+		if _, ok := f.users[record.Name]; ok {
+			continue
+		}
+		user := &feedUser{User: User{
+			Username: record.Name,
+			Color:    record.Color,
+			Mood:     record.Mood,
+		}}
+		if record.SessionID != nil {
+			user.LastSessionId = record.SessionID.String()
+		}
+		if record.SessionCreatedAt != nil {
+			user.lastSeen = *record.SessionCreatedAt
+		}
+		user.IsActive = f.connections[record.Name] > 0
+		f.users[record.Name] = user
+	}
+}
+
+// list returns the users, most recently seen first. Callers must hold the feed
+// lock.
+func (f *userFeed) list() []User {
+	users := make([]*feedUser, 0, len(f.users))
+	for _, user := range f.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].lastSeen.Equal(users[j].lastSeen) {
+			return users[i].lastSeen.After(users[j].lastSeen)
+		}
+		return users[i].Username < users[j].Username
+	})
+
+	list := make([]User, 0, len(users))
+	for _, user := range users {
+		list = append(list, user.User)
+	}
+	return list
+}
+
+// change applies fn to the user, creating it if needed, and pushes the result
+// to every subscriber.
+func (f *userFeed) change(username string, fn func(*feedUser)) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[username]
+	if !ok {
+		user = &feedUser{User: User{Username: username}}
+		f.users[username] = user
+	}
+	fn(user)
+	user.lastSeen = time.Now()
+
+	event, err := NewEvent(EventUserUpdate, user.User)
+	if err != nil {
+		f.logger.Printf("failed to marshal user update: %v", err)
+		return
+	}
+	f.publish(event)
+}
+
+// connect marks the user as active when one of its clients joins.
+func (f *userFeed) connect(c *Client) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	f.connections[c.username]++
+	f.mu.Unlock()
+
+	f.change(c.username, func(user *feedUser) {
+		if user.Color == 0 && user.Mood == "" {
+			user.Color = c.color
+			user.Mood = c.mood
+		}
+		user.IsActive = true
+		user.LastSessionId = c.session.String()
+	})
+}
+
+// disconnect marks the user as inactive once its last client has left.
+func (f *userFeed) disconnect(c *Client) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	f.connections[c.username]--
+	active := f.connections[c.username] > 0
+	if !active {
+		delete(f.connections, c.username)
+	}
+	f.mu.Unlock()
+
+	f.change(c.username, func(user *feedUser) {
+		user.IsActive = active
+	})
+}
+
+// updateProfile records a changed color or mood.
+func (f *userFeed) updateProfile(record database.User) {
+	f.change(record.Name, func(user *feedUser) {
+		user.Color = record.Color
+		user.Mood = record.Mood
+	})
+}
+
+// publish queues the event for every subscriber, dropping subscribers that
+// have fallen too far behind. Callers must hold the feed lock.
+func (f *userFeed) publish(event Event) {
+	for sub := range f.subscribers {
+		select {
+		case sub.egress <- event:
+		default:
+			f.logger.Printf("dropping slow users feed subscriber")
+			sub.conn.Close()
+		}
+	}
+}
+
+func (f *userFeed) subscribe(sub *feedSubscriber) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	event, err := NewEvent(EventUsers, f.list())
+	if err != nil {
+		return err
+	}
+	sub.egress <- event
+	f.subscribers[sub] = true
+	return nil
+}
+
+func (f *userFeed) unsubscribe(sub *feedSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[sub]; ok {
+		delete(f.subscribers, sub)
+		close(sub.done)
+		sub.conn.Close()
+	}
+}
+
+// readMsgs discards anything the subscriber sends, returning once the
+// connection closes.
+func (s *feedSubscriber) readMsgs(f *userFeed) {
+	defer f.unsubscribe(s)
+
+	for {
+		if _, _, err := s.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				f.logger.Printf("error reading users feed: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func (s *feedSubscriber) writeMsgs(f *userFeed) {
+	for {
+		select {
+		case event := <-s.egress:
+			data, err := json.Marshal(event)
+			if err != nil {
+				f.logger.Printf("failed to marshal users feed event: %v", err)
+				continue
+			}
+			if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				s.conn.Close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// updateProfile applies a changed color or mood to the user's connected
+// clients and the users feed.
+func (m *Manager) updateProfile(record database.User) {
+	m.Lock()
+	rooms := make(map[string]bool)
+	for client := range m.Clients {
+		if client.username == record.Name {
+			client.color = record.Color
+			client.mood = record.Mood
+			rooms[client.room] = true
+		}
+	}
+	m.Unlock()
+
+	for room := range rooms {
+		m.broadcastRoomState(room)
+	}
+	m.users.updateProfile(record)
+}
+
+// initiateUsersFeed upgrades a /ws/users connection and streams the active
+// users feed to it: the full list first, then a user_update for every change.
+func (m *Manager) initiateUsersFeed(c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		m.logger.Printf("%s, error while Upgrading websocket connection\n", err.Error())
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	sub := &feedSubscriber{
+		conn:   conn,
+		egress: make(chan Event, egressBuffer),
+		done:   make(chan struct{}),
+	}
+	if err := m.users.subscribe(sub); err != nil {
+		m.logger.Printf("error subscribing to users feed: %v", err)
+		conn.Close()
+		return
+	}
+
+	go sub.readMsgs(m.users)
+	go sub.writeMsgs(m.users)
+}
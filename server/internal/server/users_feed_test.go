@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+)
+
+func receiveFeed(t *testing.T, sub *feedSubscriber, eventType string, v interface{}) {
+	t.Helper()
+	select {
+	case event := <-sub.egress:
+		if event.Type != eventType {
+			t.Fatalf("expected %s, got %s", eventType, event.Type)
+		}
+		if err := json.Unmarshal(event.Payload, v); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %s", eventType)
+	}
+}
+
+func TestUserFeed(t *testing.T) {
+	db := &fakeDB{users: []database.User{{Name: "alice", Color: 3, Mood: "😀"}}}
+	feed := newUserFeed(db, log.Default())
+	feed.load()
+	sub := &feedSubscriber{egress: make(chan Event, 8), done: make(chan struct{})}
+
+	if err := feed.subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+	var users []User
+	receiveFeed(t, sub, EventUsers, &users)
+	if len(users) != 1 || users[0].Username != "alice" || users[0].IsActive {
+		t.Fatalf("unexpected initial users: %+v", users)
+	}
+
+	m := newTestManager(t)
+	first := addTestClient(m, 0, 0)
+	first.username = "alice"
+	second := addTestClient(m, 0, 0)
+	second.username = "alice"
+
+	var user User
+	feed.connect(first)
+	receiveFeed(t, sub, EventUserUpdate, &user)
+	if !user.IsActive || user.Color != 3 || user.LastSessionId != first.session.String() {
+		t.Fatalf("expected alice to be active, got %+v", user)
+	}
+
+	feed.connect(second)
+	receiveFeed(t, sub, EventUserUpdate, &user)
+	feed.disconnect(first)
+	receiveFeed(t, sub, EventUserUpdate, &user)
+	if !user.IsActive {
+		t.Fatalf("expected alice to stay active while connected elsewhere")
+	}
+
+	feed.disconnect(second)
+	receiveFeed(t, sub, EventUserUpdate, &user)
+	if user.IsActive {
+		t.Fatalf("expected alice to be inactive after the last client left")
+	}
+
+	feed.updateProfile(database.User{Name: "alice", Color: 5, Mood: "🎉"})
+	receiveFeed(t, sub, EventUserUpdate, &user)
+	if user.Color != 5 || user.Mood != "🎉" {
+		t.Fatalf("expected the profile update, got %+v", user)
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	errUsernameChars    = errors.New("username may only contain letters, digits, '_', '-' and '.'")
+	errUsernameReserved = errors.New("username is reserved")
+	errDeniedWord       = errors.New("username contains a word that is not allowed")
+	errMoodEmoji        = errors.New("mood must be a single emoji")
+)
+
+// reservedUsernames can't be taken by clients so they can't pass themselves
+// off as the server or its operators.
+var reservedUsernames = map[string]bool{
+	"admin":     true,
+	"anonymous": true,
+	"moderator": true,
+	"root":      true,
+	"server":    true,
+	"system":    true,
+}
+
+// profileRules validates the usernames and moods clients choose.
+type profileRules struct {
+	minLength int
+	maxLength int
+	denyWords []string
+}
+
+func newProfileRules(minLength, maxLength int, denyWords []string) *profileRules {
+	words := make([]string, 0, len(denyWords))
+	for _, word := range denyWords {
+		words = append(words, normaliseWord(word))
+	}
+	return &profileRules{
+		minLength: minLength,
+		maxLength: maxLength,
+		denyWords: words,
+	}
+}
+
+// normaliseWord lowercases s and strips the separators allowed in usernames,
+// so "Bad_Word" is caught by a deny word of "badword".
+func normaliseWord(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.', ' ':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+func (p *profileRules) validUsername(username string) error {
+	length := len([]rune(username))
+	if length < p.minLength || length > p.maxLength {
+		return fmt.Errorf("username must be between %d and %d characters", p.minLength, p.maxLength)
+	}
+
+	for _, r := range username {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			return errUsernameChars
+		}
+	}
+
+	if reservedUsernames[strings.ToLower(username)] {
+		return errUsernameReserved
+	}
+
+	normalised := normaliseWord(username)
+	for _, word := range p.denyWords {
+		if word != "" && strings.Contains(normalised, word) {
+			return errDeniedWord
+		}
+	}
+	return nil
+}
+
+func validMood(mood string) error {
+	if !singleEmoji(mood) {
+		return errMoodEmoji
+	}
+	return nil
+}
+
+// singleEmoji reports whether s is exactly one emoji grapheme: a flag, a
+// keycap, or a pictograph with optional presentation selectors, skin tones and
+// tags, possibly joined to further pictographs with zero width joiners.
+func singleEmoji(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return false
+	}
+
+	if isRegionalIndicator(runes[0]) {
+		return len(runes) == 2 && isRegionalIndicator(runes[1])
+	}
+
+	if strings.ContainsRune("0123456789#*", runes[0]) {
+		i := 1
+		if i < len(runes) && runes[i] == 0xFE0F {
+			i++
+		}
+		return i == len(runes)-1 && runes[i] == 0x20E3
+	}
+
+	i := 0
+	for {
+		if i >= len(runes) || !isPictograph(runes[i]) {
+			return false
+		}
+		i++
+		for i < len(runes) && isEmojiModifier(runes[i]) {
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != 0x200D {
+			return false
+		}
+		i++
+	}
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
+
+func isPictograph(r rune) bool {
+	return unicode.Is(extendedPictographic, r)
+}
+
+// extendedPictographic holds the code points with the Unicode
+// Extended_Pictographic property, from emoji-data.txt in Unicode 15.1.
+var extendedPictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x00A9, 0x00A9, 1},
+		{0x00AE, 0x00AE, 1},
+		{0x203C, 0x203C, 1},
+		{0x2049, 0x2049, 1},
+		{0x2122, 0x2122, 1},
+		{0x2139, 0x2139, 1},
+		{0x2194, 0x2199, 1},
+		{0x21A9, 0x21AA, 1},
+		{0x231A, 0x231B, 1},
+		{0x2328, 0x2328, 1},
+		{0x2388, 0x2388, 1},
+		{0x23CF, 0x23CF, 1},
+		{0x23E9, 0x23F3, 1},
+		{0x23F8, 0x23FA, 1},
+		{0x24C2, 0x24C2, 1},
+		{0x25AA, 0x25AB, 1},
+		{0x25B6, 0x25B6, 1},
+		{0x25C0, 0x25C0, 1},
+		{0x25FB, 0x25FE, 1},
+		{0x2600, 0x2605, 1},
+		{0x2607, 0x2612, 1},
+		{0x2614, 0x2685, 1},
+		{0x2690, 0x2705, 1},
+		{0x2708, 0x2712, 1},
+		{0x2714, 0x2714, 1},
+		{0x2716, 0x2716, 1},
+		{0x271D, 0x271D, 1},
+		{0x2721, 0x2721, 1},
+		{0x2728, 0x2728, 1},
+		{0x2733, 0x2734, 1},
+		{0x2744, 0x2744, 1},
+		{0x2747, 0x2747, 1},
+		{0x274C, 0x274C, 1},
+		{0x274E, 0x274E, 1},
+		{0x2753, 0x2755, 1},
+		{0x2757, 0x2757, 1},
+		{0x2763, 0x2767, 1},
+		{0x2795, 0x2797, 1},
+		{0x27A1, 0x27A1, 1},
+		{0x27B0, 0x27B0, 1},
+		{0x27BF, 0x27BF, 1},
+		{0x2934, 0x2935, 1},
+		{0x2B05, 0x2B07, 1},
+		{0x2B1B, 0x2B1C, 1},
+		{0x2B50, 0x2B50, 1},
+		{0x2B55, 0x2B55, 1},
+		{0x3030, 0x3030, 1},
+		{0x303D, 0x303D, 1},
+		{0x3297, 0x3297, 1},
+		{0x3299, 0x3299, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1F000, 0x1F0FF, 1},
+		{0x1F10D, 0x1F10F, 1},
+		{0x1F12F, 0x1F12F, 1},
+		{0x1F16C, 0x1F171, 1},
+		{0x1F17E, 0x1F17F, 1},
+		{0x1F18E, 0x1F18E, 1},
+		{0x1F191, 0x1F19A, 1},
+		{0x1F1AD, 0x1F1E5, 1},
+		{0x1F201, 0x1F20F, 1},
+		{0x1F21A, 0x1F21A, 1},
+		{0x1F22F, 0x1F22F, 1},
+		{0x1F232, 0x1F23A, 1},
+		{0x1F23C, 0x1F23F, 1},
+		{0x1F249, 0x1F3FA, 1},
+		{0x1F400, 0x1F53D, 1},
+		{0x1F546, 0x1F64F, 1},
+		{0x1F680, 0x1F6FF, 1},
+		{0x1F774, 0x1F77F, 1},
+		{0x1F7D5, 0x1F7FF, 1},
+		{0x1F80C, 0x1F80F, 1},
+		{0x1F848, 0x1F84F, 1},
+		{0x1F85A, 0x1F85F, 1},
+		{0x1F888, 0x1F88F, 1},
+		{0x1F8AE, 0x1F8FF, 1},
+		{0x1F90C, 0x1F93A, 1},
+		{0x1F93C, 0x1F945, 1},
+		{0x1F947, 0x1FAFF, 1},
+		{0x1FC00, 0x1FFFD, 1},
+	},
+	LatinOffset: 2,
+}
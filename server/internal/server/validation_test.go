@@ -0,0 +1,62 @@
+package server
+
+import "testing"
+
+func TestValidUsername(t *testing.T) {
+	p := newProfileRules(2, 12, []string{"Bad-Word"})
+
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"alice", true},
+		{"zoë_2.0", true},
+		{"", false},
+		{"a", false},
+		{"averyverylongname", false},
+		{"bob smith", false},
+		{"<script>", false},
+		{"Admin", false},
+		{"my_badword", false},
+		{"BAD_word9", false},
+	}
+	for _, tt := range tests {
+		if err := p.validUsername(tt.username); (err == nil) != tt.valid {
+			t.Errorf("validUsername(%q) = %v, want valid %v", tt.username, err, tt.valid)
+		}
+	}
+}
+
+func TestValidMood(t *testing.T) {
+	tests := []struct {
+		mood  string
+		valid bool
+	}{
+		{"😀", true},
+		{"❤️", true},
+		{"👍🏽", true},
+		{"👩‍💻", true},
+		{"🇬🇧", true},
+		{"1️⃣", true},
+		{"", false},
+		{"a", false},
+		{"😀😀", false},
+		{"🇬", false},
+		{"happy", false},
+		{"😀 ", false},
+		{"↔️", true},
+		{"⌚", true},
+		{"⭐", true},
+		{"←", false},
+		{"⌀", false},
+		{"⏐", false},
+		{"⬀", false},
+		{"☆", false},
+		{"➔", false},
+	}
+	for _, tt := range tests {
+		if err := validMood(tt.mood); (err == nil) != tt.valid {
+			t.Errorf("validMood(%q) = %v, want valid %v", tt.mood, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// APIKeyHeader carries the key for the position ingestion API.
+const APIKeyHeader = "X-API-Key"
+
+// virtualTransport stands in for the connection of a client driven over
+// REST. Nothing is read from it and everything written to it is dropped, so
+// the client goes through the usual read and write loops until it is closed.
+type virtualTransport struct {
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newVirtualTransport() *virtualTransport {
+	return &virtualTransport{closed: make(chan struct{})}
+}
+
+func (t *virtualTransport) ReadMessage() (int, []byte, error) {
+	<-t.closed
+	return 0, nil, errTransportClosed
+}
+
+func (t *virtualTransport) WriteMessage(messageType int, data []byte) error {
+	select {
+	case <-t.closed:
+		return errTransportClosed
+	default:
+		return nil
+	}
+}
+
+func (t *virtualTransport) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType == websocket.CloseMessage {
+		return t.Close()
+	}
+	return nil
+}
+
+func (t *virtualTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return nil
+}
+
+type VirtualPosition struct {
+	Username string  `json:"username" binding:"required"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Color    *int    `json:"color"`
+	Mood     string  `json:"mood"`
+}
+
+type PositionsRequest struct {
+	Positions []VirtualPosition `json:"positions" binding:"required,min=1,dive"`
+}
+
+type VirtualClient struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Room     string `json:"room"`
+}
+
+func virtualKey(room, username string) string {
+	return room + "/" + username
+}
+
+// ingestAuth only lets requests through that carry one of the configured API
+// keys. Without keys the ingestion API is disabled.
+func ingestAuth(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(keys) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "position ingestion is disabled"})
+			return
+		}
+
+		given := c.GetHeader(APIKeyHeader)
+		for _, key := range keys {
+			if subtle.ConstantTimeCompare([]byte(given), []byte(key)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+	}
+}
+
+// virtualClient returns the virtual client for the username in the room,
+// connecting a new one if there isn't one yet.
+func (m *Manager) virtualClient(room, username string) *Client {
+	key := virtualKey(room, username)
+
+	m.RLock()
+	client, ok := m.virtuals[key]
+	m.RUnlock()
+	if ok {
+		return client
+	}
+
+	// NewClient reads the user from the database, so it is built without the
+	// lock and dropped if another request connected the username meanwhile.
+	fresh := NewClient(username, room, newVirtualTransport(), m)
+	fresh.virtual = true
+	fresh.lastUpdate = time.Now()
+
+	m.Lock()
+	client, ok = m.virtuals[key]
+	if !ok {
+		client = fresh
+		m.virtuals[key] = client
+	}
+	m.Unlock()
+
+	if ok {
+		return client
+	}
+
+	m.logger.Printf("New virtual client %s in %s\n", username, room)
+
+	m.addClient(client)
+
+	go client.readMsgs()
+	go client.writeMsg()
+
+	return client
+}
+
+// moveVirtual applies a position to a virtual client through the same handler
+// as a WebSocket update, so it is broadcast like any other cursor.
+func (m *Manager) moveVirtual(client *Client, position VirtualPosition) error {
+	now := time.Now()
+
+	m.Lock()
+	if position.Color != nil {
+		client.color = *position.Color
+	}
+	if position.Mood != "" && position.Mood != client.mood {
+		m.webhooks.dispatch(EventMoodChange, MoodChange{
+			Username: client.username,
+			Mood:     position.Mood,
+			Previous: client.mood,
+		})
+		client.mood = position.Mood
+	}
+	// a zero delta would make the velocity infinite
+	delta := max(now.Sub(client.lastUpdate).Milliseconds(), 1)
+	client.lastUpdate = now
+	m.Unlock()
+
+	event, err := NewEvent(EventUpdatePosition, UpdatePositionEvent{
+		X:     position.X,
+		Y:     position.Y,
+		Delta: int(delta),
+	})
+	if err != nil {
+		return err
+	}
+	return m.routeEvent(event, client)
+}
+
+// expireVirtuals disconnects virtual clients that haven't been updated within
+// virtualTTL. They are forgotten straight away, so an update arriving while
+// they disconnect starts a new client instead of moving the expiring one.
+func (m *Manager) expireVirtuals(now time.Time) {
+	m.Lock()
+	var expired []*Client
+	for key, client := range m.virtuals {
+		if now.Sub(client.lastUpdate) > m.virtualTTL {
+			expired = append(expired, client)
+			delete(m.virtuals, key)
+		}
+	}
+	m.Unlock()
+
+	for _, client := range expired {
+		m.logger.Printf("Virtual client %s in %s timed out\n", client.username, client.room)
+		client.conn.Close()
+	}
+}
+
+func (m *Manager) runVirtuals() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			m.expireVirtuals(now)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// forgetVirtual drops a disconnecting virtual client so the next update for
+// its username starts a new one. Callers must hold the manager lock.
+func (m *Manager) forgetVirtual(c *Client) {
+	key := virtualKey(c.room, c.username)
+	if m.virtuals[key] == c {
+		delete(m.virtuals, key)
+	}
+}
+
+func (s *Server) registerIngestRoutes(r gin.IRouter) {
+	ingest := r.Group("/", ingestAuth(s.manager.ingestKeys))
+
+	ingest.POST("/rooms/:room/positions", s.postPositionsHandler)
+}
+
+func (s *Server) postPositionsHandler(c *gin.Context) {
+	room := c.Param("room")
+
+	var req PositionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, position := range req.Positions {
+		if err := s.manager.profile.validUsername(position.Username); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "username": position.Username})
+			return
+		}
+		if !validPosition(position.X, position.Y) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": errInvalidPosition.Error(), "username": position.Username})
+			return
+		}
+		if position.Mood != "" {
+			if err := validMood(position.Mood); err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "username": position.Username})
+				return
+			}
+		}
+		ban, ok, err := s.manager.bans.match(position.Username, "")
+		if err != nil {
+			s.manager.logger.Printf("error loading bans: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": errBansUnavailable.Error()})
+			return
+		}
+		if ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": banReason(ban), "username": position.Username})
+			return
+		}
+	}
+
+	clients := []VirtualClient{}
+	for _, position := range req.Positions {
+		client := s.manager.virtualClient(room, position.Username)
+		if err := s.manager.moveVirtual(client, position); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "username": position.Username})
+			return
+		}
+		clients = append(clients, VirtualClient{
+			ID:       client.id.String(),
+			Username: client.username,
+			Room:     client.room,
+		})
+	}
+
+	c.JSON(http.StatusAccepted, clients)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostPositionsHandler(t *testing.T) {
+	m := newTestManager(t)
+	listener := addTestClient(m, 0, 0)
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.POST("/rooms/:room/positions", ingestAuth([]string{"key"}), s.postPositionsHandler)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/rooms/lobby/positions", strings.NewReader(body))
+		req.Header.Set(APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	body := `{"positions":[{"username":"sensor-1","x":40,"y":60,"mood":"🤖"}]}`
+	if rr := post("wrong", body); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong key to be refused, got %d", rr.Code)
+	}
+
+	rr := post("key", body)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("got status %d: %s", rr.Code, rr.Body.String())
+	}
+	var clients []VirtualClient
+	if err := json.Unmarshal(rr.Body.Bytes(), &clients); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].Username != "sensor-1" {
+		t.Fatalf("unexpected virtual clients: %+v", clients)
+	}
+
+	event := receive(t, listener, "broadcast")
+	for !strings.Contains(string(event.Payload), "sensor-1") {
+		event = receive(t, listener, "broadcast")
+	}
+
+	if rr := post("key", body); rr.Code != http.StatusAccepted {
+		t.Fatalf("got status %d on the second update", rr.Code)
+	}
+	m.RLock()
+	virtual := m.virtuals[virtualKey(DefaultRoom, "sensor-1")]
+	count := len(m.virtuals)
+	m.RUnlock()
+	if count != 1 || virtual.id.String() != clients[0].ID {
+		t.Fatalf("expected updates to reuse the virtual client")
+	}
+
+	if rr := post("key", `{"positions":[{"username":"x"}]}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected an invalid username to be refused, got %d", rr.Code)
+	}
+}
+
+func TestExpireVirtuals(t *testing.T) {
+	m := newTestManager(t)
+	client := m.virtualClient(DefaultRoom, "bot")
+
+	m.expireVirtuals(time.Now())
+	m.RLock()
+	_, ok := m.Clients[client]
+	m.RUnlock()
+	if !ok {
+		t.Fatalf("expected a fresh virtual client to stay connected")
+	}
+
+	m.expireVirtuals(time.Now().Add(2 * m.virtualTTL))
+	if next := m.virtualClient(DefaultRoom, "bot"); next == client {
+		t.Fatalf("expected an update after expiry to start a new virtual client")
+	}
+
+	deadline := time.After(time.Second)
+	for {
+		m.RLock()
+		_, connected := m.Clients[client]
+		m.RUnlock()
+		if !connected {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("expected the virtual client to time out")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestConcurrentVirtualUpdates(t *testing.T) {
+	m := newTestManager(t)
+	client := m.virtualClient(DefaultRoom, "bot")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.moveVirtual(client, VirtualPosition{X: float64(i), Y: float64(i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	m.RLock()
+	x, y := client.state.X, client.state.Y
+	m.RUnlock()
+	if x != y || x < 0 || x > 7 {
+		t.Fatalf("expected the last update's position, got (%v, %v)", x, y)
+	}
+}
@@ -0,0 +1,461 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventUserJoin   = "user_join"
+	EventUserLeave  = "user_leave"
+	EventMoodChange = "mood_change"
+
+	// headers sent with every webhook delivery
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+var errPrivateWebhook = errors.New("webhooks may not be delivered to loopback, link-local or private addresses")
+
+type Webhook struct {
+	ID        uuid.UUID  `json:"id"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	Secret    string     `json:"secret,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// newWebhook converts a registration without its secret, which is only shown
+// when the webhook is created.
+func newWebhook(w database.Webhook) Webhook {
+	return Webhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+type NewWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events"`
+}
+
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID  `json:"id"`
+	DeliveryID uuid.UUID  `json:"delivery_id"`
+	Event      string     `json:"event"`
+	Attempt    int        `json:"attempt"`
+	StatusCode int        `json:"status_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+func newWebhookDelivery(d database.WebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:         d.ID,
+		DeliveryID: d.DeliveryID,
+		Event:      d.Event,
+		Attempt:    d.Attempt,
+		StatusCode: d.StatusCode,
+		Error:      d.Error,
+		DurationMs: d.DurationMs,
+		CreatedAt:  d.CreatedAt,
+	}
+}
+
+type WebhookDeadLetter struct {
+	ID        uuid.UUID       `json:"id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+}
+
+func newWebhookDeadLetter(l database.WebhookDeadLetter) WebhookDeadLetter {
+	return WebhookDeadLetter{
+		ID:        l.ID,
+		Event:     l.Event,
+		Payload:   l.Payload,
+		Attempts:  l.Attempts,
+		LastError: l.LastError,
+		CreatedAt: l.CreatedAt,
+	}
+}
+
+// WebhookUser is the data sent with user_join and user_leave.
+type WebhookUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Room     string `json:"room"`
+}
+
+// MoodChange is the data sent with mood_change.
+type MoodChange struct {
+	Username string `json:"username"`
+	Mood     string `json:"mood"`
+	Previous string `json:"previous"`
+}
+
+// newWebhookSecret returns a random key for signing a webhook's deliveries.
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// signWebhook signs the timestamp and body with the webhook's secret.
+// Receivers recompute the HMAC over "<timestamp>.<body>" to check a delivery
+// came from us and reject old timestamps to stop replays.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// privateAddr reports whether ip is an address a webhook must not reach
+// unless private targets are allowed: loopback, link-local, private or
+// unspecified.
+func privateAddr(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// checkWebhookURL rejects URLs that aren't http(s) and, unless allowPrivate,
+// hosts that are obviously internal. Host names are checked again when a
+// delivery connects, once they have been resolved.
+func checkWebhookURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("webhook URL must be http or https")
+	}
+	if u.Hostname() == "" {
+		return errors.New("webhook URL has no host")
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateWebhook
+	}
+	if ip := net.ParseIP(host); ip != nil && privateAddr(ip) {
+		return errPrivateWebhook
+	}
+	return nil
+}
+
+// newWebhookClient returns the client deliveries are posted with. Redirects
+// are not followed, and unless allowPrivate every connection is checked after
+// DNS resolution so a public name can't lead to an internal address.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateAddr(ip) {
+				return errPrivateWebhook
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// webhookDispatcher posts presence events to the registered webhooks. The
+// registrations are cached and reloaded whenever they change over REST.
+// Failed deliveries are retried with exponential backoff and end up in the
+// dead-letter table once every attempt has failed, or when the manager closes
+// while they wait to retry.
+type webhookDispatcher struct {
+	db     database.Service
+	client *http.Client
+	logger *log.Logger
+
+	// whether webhooks may target loopback, link-local and private addresses
+	allowPrivate bool
+
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	// closed when the manager closes, cutting retry backoffs short
+	done <-chan struct{}
+
+	mu     sync.RWMutex
+	hooks  []database.Webhook
+	loaded bool
+
+	pending sync.WaitGroup
+}
+
+func newWebhookDispatcher(db database.Service, logger *log.Logger, done <-chan struct{}, allowPrivate bool, maxAttempts int, backoff, maxBackoff time.Duration) *webhookDispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &webhookDispatcher{
+		db:           db,
+		client:       newWebhookClient(allowPrivate),
+		logger:       logger,
+		allowPrivate: allowPrivate,
+		maxAttempts:  maxAttempts,
+		backoff:      backoff,
+		maxBackoff:   maxBackoff,
+		done:         done,
+	}
+}
+
+func (d *webhookDispatcher) reload() error {
+	hooks, err := d.db.GetWebhooks()
+	if err != nil {
+		return err
+	}
+
+	// deliveries are always signed, so webhooks registered before secrets
+	// existed are skipped until one is set
+	var signed []database.Webhook
+	for _, hook := range hooks {
+		if hook.Secret == "" {
+			d.logger.Printf("webhook %s has no secret, not delivering to %s until one is set", hook.ID, hook.URL)
+			continue
+		}
+		signed = append(signed, hook)
+	}
+
+	d.mu.Lock()
+	d.hooks = signed
+	d.loaded = true
+	d.mu.Unlock()
+	return nil
+}
+
+// subscribers returns the webhooks registered for the event. A webhook with no
+// events listed receives every event.
+func (d *webhookDispatcher) subscribers(event string) []database.Webhook {
+	d.mu.RLock()
+	loaded := d.loaded
+	d.mu.RUnlock()
+
+	if !loaded {
+		if err := d.reload(); err != nil {
+			d.logger.Printf("error loading webhooks: %v", err)
+			return nil
+		}
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var hooks []database.Webhook
+	for _, hook := range d.hooks {
+		if len(hook.Events) == 0 {
+			hooks = append(hooks, hook)
+			continue
+		}
+		for _, e := range hook.Events {
+			if e == event {
+				hooks = append(hooks, hook)
+				break
+			}
+		}
+	}
+	return hooks
+}
+
+// dispatch delivers the event to every subscribed webhook in the background.
+func (d *webhookDispatcher) dispatch(event string, data interface{}) {
+	if d == nil {
+		return
+	}
+
+	d.pending.Add(1)
+	go func() {
+		defer d.pending.Done()
+
+		hooks := d.subscribers(event)
+		if len(hooks) == 0 {
+			return
+		}
+
+		body, err := json.Marshal(WebhookPayload{
+			Event:     event,
+			Data:      data,
+			Timestamp: time.Now().UTC(),
+		})
+		if err != nil {
+			d.logger.Printf("failed to marshal webhook payload: %v", err)
+			return
+		}
+
+		for _, hook := range hooks {
+			d.pending.Add(1)
+			go func(hook database.Webhook) {
+				defer d.pending.Done()
+				d.deliver(hook, event, body)
+			}(hook)
+		}
+	}()
+}
+
+// wait blocks until every dispatched event has been delivered or given up on.
+func (d *webhookDispatcher) wait() {
+	if d == nil {
+		return
+	}
+	d.pending.Wait()
+}
+
+// retryDelay is the backoff before the given retry: backoff, doubling with
+// every attempt up to maxBackoff.
+func (d *webhookDispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.backoff << (attempt - 1)
+	if delay <= 0 || (d.maxBackoff > 0 && delay > d.maxBackoff) {
+		return d.maxBackoff
+	}
+	return delay
+}
+
+// deliver posts the body to the webhook until it is accepted or the attempts
+// run out, logging every attempt.
+func (d *webhookDispatcher) deliver(hook database.Webhook, event string, body []byte) {
+	deliveryID := uuid.New()
+
+	var lastError string
+	attempt := 0
+	for attempt < d.maxAttempts {
+		if attempt > 0 && !d.backOff(attempt) {
+			break
+		}
+		attempt++
+
+		start := time.Now()
+		status, err := d.post(hook, deliveryID, event, body)
+		record := database.WebhookDelivery{
+			ID:         uuid.New(),
+			DeliveryID: deliveryID,
+			WebhookID:  hook.ID,
+			Event:      event,
+			Attempt:    attempt,
+			StatusCode: status,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if logErr := d.db.LogWebhookDelivery(record); logErr != nil {
+			d.logger.Printf("error logging webhook delivery: %v", logErr)
+		}
+
+		if err == nil {
+			return
+		}
+		lastError = err.Error()
+	}
+
+	d.logger.Printf("giving up delivering %s to %s: %s", event, hook.URL, lastError)
+	err := d.db.CreateWebhookDeadLetter(database.WebhookDeadLetter{
+		ID:        deliveryID,
+		WebhookID: hook.ID,
+		Event:     event,
+		Payload:   body,
+		Attempts:  attempt,
+		LastError: lastError,
+	})
+	if err != nil {
+		d.logger.Printf("error dead-lettering webhook delivery: %v", err)
+	}
+}
+
+// backOff waits before the given retry. It returns false if the manager
+// closed first, so shutdown isn't held up by deliveries waiting to retry.
+func (d *webhookDispatcher) backOff(attempt int) bool {
+	timer := time.NewTimer(d.retryDelay(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.done:
+		return false
+	}
+}
+
+// post makes a single delivery attempt, failing on any response outside 2xx,
+// redirects included.
+func (d *webhookDispatcher) post(hook database.Webhook, deliveryID uuid.UUID, event string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID.String())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, signWebhook(hook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
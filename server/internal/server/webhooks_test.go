@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	var calls atomic.Int32
+	var signed atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := signWebhook("secret", r.Header.Get(WebhookTimestampHeader), body)
+		signed.Store(r.Header.Get(WebhookSignatureHeader) == expected)
+
+		// fail the first attempt so the delivery is retried
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	db := &fakeDB{webhooks: []database.Webhook{
+		{ID: uuid.New(), URL: receiver.URL, Events: []string{EventUserJoin}, Secret: "secret"},
+	}}
+	d := newWebhookDispatcher(db, log.Default(), nil, true, 3, time.Millisecond, 10*time.Millisecond)
+
+	d.dispatch(EventUserJoin, WebhookUser{Username: "alice", Room: DefaultRoom})
+	d.dispatch(EventUserLeave, WebhookUser{Username: "alice", Room: DefaultRoom})
+	d.wait()
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected one retry of the subscribed event, got %d calls", calls.Load())
+	}
+	if !signed.Load() {
+		t.Errorf("expected the delivery to carry a valid signature")
+	}
+	if len(db.deliveries) != 2 || db.deliveries[0].StatusCode != http.StatusServiceUnavailable || db.deliveries[1].Attempt != 2 {
+		t.Errorf("unexpected delivery log: %+v", db.deliveries)
+	}
+	if db.deliveries[0].DeliveryID != db.deliveries[1].DeliveryID {
+		t.Errorf("expected retries to share a delivery id")
+	}
+	if len(db.deadLetters) != 0 {
+		t.Errorf("expected no dead letters, got %d", len(db.deadLetters))
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	db := &fakeDB{webhooks: []database.Webhook{{ID: uuid.New(), URL: receiver.URL, Secret: "secret"}}}
+	d := newWebhookDispatcher(db, log.Default(), nil, true, 3, time.Millisecond, 10*time.Millisecond)
+
+	d.dispatch(EventMoodChange, MoodChange{Username: "alice", Mood: "🎉"})
+	d.wait()
+
+	if calls.Load() != 3 {
+		t.Fatalf("expected every attempt to be made, got %d calls", calls.Load())
+	}
+	if len(db.deadLetters) != 1 {
+		t.Fatalf("expected the event to be dead-lettered, got %d", len(db.deadLetters))
+	}
+	letter := db.deadLetters[0]
+	if letter.Event != EventMoodChange || letter.Attempts != 3 || len(letter.Payload) == 0 {
+		t.Errorf("unexpected dead letter: %+v", letter)
+	}
+}
+
+func TestWebhookBackoffStopsOnClose(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	db := &fakeDB{webhooks: []database.Webhook{{ID: uuid.New(), URL: receiver.URL, Secret: "secret"}}}
+	done := make(chan struct{})
+	d := newWebhookDispatcher(db, log.Default(), done, true, 3, time.Minute, time.Minute)
+
+	d.dispatch(EventMoodChange, MoodChange{Username: "alice", Mood: "🎉"})
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+
+	delivered := make(chan struct{})
+	go func() {
+		d.wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("expected closing to cut the retry backoff short")
+	}
+
+	if calls.Load() != 1 || len(db.deadLetters) != 1 || db.deadLetters[0].Attempts != 1 {
+		t.Fatalf("expected the delivery to be dead-lettered after one attempt, got %d calls and %+v", calls.Load(), db.deadLetters)
+	}
+}
+
+func TestWebhookUnsignedSkipped(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	m := newTestManager(t, WithPrivateWebhooks(true))
+	db := m.db.(*fakeDB)
+	id := uuid.New()
+	db.webhooks = []database.Webhook{{ID: id, URL: receiver.URL}}
+
+	m.webhooks.dispatch(EventMoodChange, MoodChange{Username: "alice", Mood: "🎉"})
+	m.webhooks.wait()
+	if calls.Load() != 0 {
+		t.Fatalf("expected a webhook without a secret not to be delivered to")
+	}
+
+	s := &Server{db: db, manager: m}
+	r := gin.New()
+	r.POST("/admin/webhooks/:id/secret", s.rotateWebhookSecretHandler)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("POST", "/admin/webhooks/"+id.String()+"/secret", nil))
+	if rr.Code != http.StatusOK || db.webhooks[0].Secret == "" {
+		t.Fatalf("expected the secret to be set, got %d", rr.Code)
+	}
+
+	m.webhooks.dispatch(EventMoodChange, MoodChange{Username: "alice", Mood: "🎉"})
+	m.webhooks.wait()
+	if calls.Load() != 1 {
+		t.Fatalf("expected the webhook to be delivered to once it has a secret, got %d calls", calls.Load())
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	d := newWebhookDispatcher(nil, log.Default(), nil, false, 5, 100*time.Millisecond, 300*time.Millisecond)
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, delay := range want {
+		if got := d.retryDelay(i + 1); got != delay {
+			t.Errorf("retryDelay(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		ok           bool
+	}{
+		{"https://hooks.example.com/cursors", false, true},
+		{"ftp://hooks.example.com", false, false},
+		{"http://localhost:8080/hooks", false, false},
+		{"http://127.0.0.1/hooks", false, false},
+		{"http://169.254.169.254/latest/meta-data", false, false},
+		{"http://10.0.0.5/hooks", false, false},
+		{"http://[::1]/hooks", false, false},
+		{"http://localhost:8080/hooks", true, true},
+	}
+	for _, tt := range tests {
+		if err := checkWebhookURL(tt.url, tt.allowPrivate); (err == nil) != tt.ok {
+			t.Errorf("checkWebhookURL(%q, %v) = %v", tt.url, tt.allowPrivate, err)
+		}
+	}
+}
+
+func TestWebhookPrivateTargetRefused(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	// registered under a name, the loopback address is only found on dialing
+	url := strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+	db := &fakeDB{webhooks: []database.Webhook{{ID: uuid.New(), URL: url, Secret: "secret"}}}
+	d := newWebhookDispatcher(db, log.Default(), nil, false, 1, time.Millisecond, time.Millisecond)
+
+	d.dispatch(EventUserJoin, WebhookUser{Username: "alice", Room: DefaultRoom})
+	d.wait()
+
+	if calls.Load() != 0 {
+		t.Fatal("expected the delivery not to reach a loopback address")
+	}
+	if len(db.deadLetters) != 1 || !strings.Contains(db.deadLetters[0].LastError, errPrivateWebhook.Error()) {
+		t.Fatalf("expected the delivery to be refused, got %+v", db.deadLetters)
+	}
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	var followed atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	}))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	db := &fakeDB{webhooks: []database.Webhook{{ID: uuid.New(), URL: receiver.URL, Secret: "secret"}}}
+	d := newWebhookDispatcher(db, log.Default(), nil, true, 1, time.Millisecond, time.Millisecond)
+
+	d.dispatch(EventUserJoin, WebhookUser{Username: "alice", Room: DefaultRoom})
+	d.wait()
+
+	if followed.Load() {
+		t.Fatal("expected the redirect not to be followed")
+	}
+	if len(db.deadLetters) != 1 {
+		t.Fatalf("expected a redirect to fail the delivery, got %d dead letters", len(db.deadLetters))
+	}
+}
+
+func TestCloseWaitsForWebhooks(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	m := newTestManager(t, WithPrivateWebhooks(true))
+	m.db.(*fakeDB).webhooks = []database.Webhook{{ID: uuid.New(), URL: receiver.URL, Secret: "secret"}}
+
+	m.webhooks.dispatch(EventMoodChange, MoodChange{Username: "alice", Mood: "🎉"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected Close to wait for the delivery until the deadline, got %v", err)
+	}
+
+	close(release)
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("expected Close to return once the delivery finished, got %v", err)
+	}
+}
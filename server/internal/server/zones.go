@@ -0,0 +1,217 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventZoneEnter = "zone_enter"
+	EventZoneLeave = "zone_leave"
+
+	ZoneShapeRect    = "rect"
+	ZoneShapePolygon = "polygon"
+)
+
+var errInvalidZone = errors.New("zone needs a rect or at least three points")
+
+type Zone struct {
+	ID     uuid.UUID            `json:"id"`
+	Room   string               `json:"room"`
+	Name   string               `json:"name"`
+	Shape  string               `json:"shape"`
+	Points []database.ZonePoint `json:"points"`
+}
+
+func newZone(z database.Zone) *Zone {
+	return &Zone{
+		ID:     z.ID,
+		Room:   z.Room,
+		Name:   z.Name,
+		Shape:  z.Shape,
+		Points: z.Points,
+	}
+}
+
+// contains reports whether (x, y) lies inside the zone's polygon, using the
+// even-odd ray casting rule.
+func (z *Zone) contains(x, y float64) bool {
+	inside := false
+	for i, j := 0, len(z.Points)-1; i < len(z.Points); j, i = i, i+1 {
+		a, b := z.Points[i], z.Points[j]
+		if (a.Y > y) != (b.Y > y) && x < (b.X-a.X)*(y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+type NewZoneRequest struct {
+	Name   string               `json:"name" binding:"required"`
+	Rect   *Viewport            `json:"rect"`
+	Points []database.ZonePoint `json:"points"`
+}
+
+// record converts the request into a zone, storing rectangles as their four
+// corners so every zone can be tested the same way.
+func (r NewZoneRequest) record(room string) (database.Zone, error) {
+	zone := database.Zone{
+		ID:   uuid.New(),
+		Room: room,
+		Name: r.Name,
+	}
+
+	switch {
+	case r.Rect != nil && r.Rect.W > 0 && r.Rect.H > 0:
+		zone.Shape = ZoneShapeRect
+		zone.Points = []database.ZonePoint{
+			{X: r.Rect.X, Y: r.Rect.Y},
+			{X: r.Rect.X + r.Rect.W, Y: r.Rect.Y},
+			{X: r.Rect.X + r.Rect.W, Y: r.Rect.Y + r.Rect.H},
+			{X: r.Rect.X, Y: r.Rect.Y + r.Rect.H},
+		}
+	case len(r.Points) >= 3:
+		zone.Shape = ZoneShapePolygon
+		zone.Points = r.Points
+	default:
+		return database.Zone{}, errInvalidZone
+	}
+	return zone, nil
+}
+
+type ZoneStats struct {
+	ZoneID     uuid.UUID `json:"zone_id"`
+	Visits     int64     `json:"visits"`
+	TotalDwell int64     `json:"total_dwell_ms"`
+	MaxDwell   int64     `json:"max_dwell_ms"`
+}
+
+// ZoneCrossing is sent to the room and to webhooks when a client enters or
+// leaves a zone. Dwell is only set when leaving.
+type ZoneCrossing struct {
+	ZoneID   uuid.UUID `json:"zone_id"`
+	Zone     string    `json:"zone"`
+	Room     string    `json:"room"`
+	ID       string    `json:"id"`
+	Username string    `json:"username"`
+	Dwell    int64     `json:"dwell_ms,omitempty"`
+	At       time.Time `json:"at"`
+
+	event string
+}
+
+// roomZones returns the zones defined for a room, loading them from the
+// database the first time the room is used. Callers must hold the manager lock.
+func (m *Manager) roomZones(room string) map[uuid.UUID]*Zone {
+	zones, ok := m.zones[room]
+	if ok {
+		return zones
+	}
+
+	zones = make(map[uuid.UUID]*Zone)
+	records, err := m.db.GetZones(room)
+	if err != nil {
+		m.logger.Printf("error loading zones for %s: %v", room, err)
+	}
+	for _, record := range records {
+		zones[record.ID] = newZone(record)
+	}
+
+	m.zones[room] = zones
+	return zones
+}
+
+func (m *Manager) addZone(zone *Zone) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.roomZones(zone.Room)[zone.ID] = zone
+}
+
+// removeZone forgets a deleted zone. Clients inside it are dropped without
+// a leave event since the zone no longer exists.
+func (m *Manager) removeZone(room string, id uuid.UUID) {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.roomZones(room), id)
+	for client := range m.Clients {
+		delete(client.zones, id)
+	}
+}
+
+func crossing(event string, zone *Zone, c *Client, at time.Time) ZoneCrossing {
+	return ZoneCrossing{
+		ZoneID:   zone.ID,
+		Zone:     zone.Name,
+		Room:     zone.Room,
+		ID:       c.id.String(),
+		Username: c.username,
+		At:       at,
+		event:    event,
+	}
+}
+
+// updateZones compares the client's position with the zones in its room and
+// returns the zones it has entered or left. Callers must hold the manager
+// lock.
+func (m *Manager) updateZones(c *Client, now time.Time) []ZoneCrossing {
+	var crossings []ZoneCrossing
+	for id, zone := range m.roomZones(c.room) {
+		enteredAt, inside := c.zones[id]
+		switch contains := zone.contains(c.state.X, c.state.Y); {
+		case contains && !inside:
+			c.zones[id] = now
+			crossings = append(crossings, crossing(EventZoneEnter, zone, c, now))
+		case !contains && inside:
+			delete(c.zones, id)
+			leave := crossing(EventZoneLeave, zone, c, now)
+			leave.Dwell = now.Sub(enteredAt).Milliseconds()
+			crossings = append(crossings, leave)
+		}
+	}
+	return crossings
+}
+
+// leaveZones leaves every zone the client is in, for when it disconnects.
+// Callers must hold the manager lock.
+func (m *Manager) leaveZones(c *Client, now time.Time) []ZoneCrossing {
+	var crossings []ZoneCrossing
+	zones := m.zones[c.room]
+	for id, enteredAt := range c.zones {
+		delete(c.zones, id)
+		zone, ok := zones[id]
+		if !ok {
+			continue
+		}
+		leave := crossing(EventZoneLeave, zone, c, now)
+		leave.Dwell = now.Sub(enteredAt).Milliseconds()
+		crossings = append(crossings, leave)
+	}
+	return crossings
+}
+
+// publishCrossings sends zone crossings to the room and to webhooks, and
+// records the dwell time of each visit.
+func (m *Manager) publishCrossings(crossings []ZoneCrossing) {
+	for _, crossing := range crossings {
+		event, err := NewEvent(crossing.event, crossing)
+		if err != nil {
+			m.logger.Printf("failed to marshal zone crossing: %v", err)
+			continue
+		}
+		m.broadcast(crossing.Room, event, nil)
+		m.webhooks.dispatch(crossing.event, crossing)
+
+		if crossing.event == EventZoneLeave {
+			dwell := time.Duration(crossing.Dwell) * time.Millisecond
+			if err := m.db.RecordZoneVisit(crossing.ZoneID, dwell); err != nil {
+				m.logger.Printf("error recording zone visit: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lloydrichards/proj_ghost-sockets/server/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestZoneContains(t *testing.T) {
+	req := NewZoneRequest{Name: "box", Rect: &Viewport{X: 0, Y: 0, W: 10, H: 10}}
+	record, err := req.record(DefaultRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rect := newZone(record)
+	if !rect.contains(5, 5) || rect.contains(15, 5) {
+		t.Fatal("unexpected containment for rect zone")
+	}
+
+	triangle := &Zone{Points: []database.ZonePoint{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}}}
+	if !triangle.contains(2, 2) || triangle.contains(8, 8) {
+		t.Fatal("unexpected containment for polygon zone")
+	}
+
+	if _, err := (NewZoneRequest{Name: "line", Points: []database.ZonePoint{{}, {X: 1}}}).record(DefaultRoom); err != errInvalidZone {
+		t.Fatalf("expected invalid zone error, got %v", err)
+	}
+}
+
+func TestZoneEnterLeave(t *testing.T) {
+	m := newTestManager(t)
+	db := m.db.(*fakeDB)
+	record, _ := NewZoneRequest{Name: "stage", Rect: &Viewport{X: 0, Y: 0, W: 100, H: 100}}.record(DefaultRoom)
+	db.zones = append(db.zones, record)
+
+	c := addTestClient(m, -50, -50)
+	move := func(payload string) {
+		if err := UpdatePosition(Event{Type: EventUpdatePosition, Payload: json.RawMessage(payload)}, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	move(`{"x":50,"y":50,"delta":100}`)
+	var enter ZoneCrossing
+	if err := json.Unmarshal(receive(t, c, EventZoneEnter).Payload, &enter); err != nil {
+		t.Fatal(err)
+	}
+	if enter.ZoneID != record.ID {
+		t.Fatalf("expected to enter %s, got %s", record.ID, enter.ZoneID)
+	}
+
+	move(`{"x":150,"y":50,"delta":100}`)
+	receive(t, c, EventZoneLeave)
+
+	if db.visits[record.ID] != 1 {
+		t.Fatalf("expected one visit to be recorded, got %d", db.visits[record.ID])
+	}
+}
+
+func TestDeleteZoneHandler(t *testing.T) {
+	m := newTestManager(t)
+	db := m.db.(*fakeDB)
+	record, _ := NewZoneRequest{Name: "stage", Rect: &Viewport{X: 0, Y: 0, W: 100, H: 100}}.record(DefaultRoom)
+	db.zones = append(db.zones, record)
+	m.roomZones(DefaultRoom)
+
+	s := &Server{db: m.db, manager: m}
+	r := gin.New()
+	r.DELETE("/rooms/:room/zones/:id", s.deleteZoneHandler)
+	remove := func(room string) int {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("DELETE", "/rooms/"+room+"/zones/"+record.ID.String(), nil))
+		return rr.Code
+	}
+
+	if code := remove("elsewhere"); code != http.StatusNotFound {
+		t.Fatalf("expected a zone in another room not to be found, got %d", code)
+	}
+	if len(db.zones) != 1 {
+		t.Fatal("expected the zone to be kept")
+	}
+
+	if code := remove(DefaultRoom); code != http.StatusNoContent {
+		t.Fatalf("expected the zone to be deleted, got %d", code)
+	}
+	if _, ok := m.zones[DefaultRoom][record.ID]; ok {
+		t.Fatal("expected the zone to stop being tracked")
+	}
+}